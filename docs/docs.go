@@ -30,7 +30,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/management.Identity"
                         }
                     },
                     "401": {
@@ -42,16 +42,16 @@ const docTemplate = `{
                 }
             }
         },
-        "/account/login": {
-            "post": {
-                "description": "Login existing user",
+        "/account/email": {
+            "patch": {
+                "description": "Request an email change of the current account, confirmed with its password and applied once the link mailed to the new address is used",
                 "consumes": [
                     "application/json"
                 ],
                 "tags": [
                     "account"
                 ],
-                "summary": "Login",
+                "summary": "ChangeEmail",
                 "parameters": [
                     {
                         "description": "body",
@@ -59,7 +59,7 @@ const docTemplate = `{
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/management.Request"
+                            "$ref": "#/definitions/management.EmailRequest"
                         }
                     }
                 ],
@@ -73,45 +73,54 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad Request"
                     },
-                    "500": {
-                        "description": "Internal Server Error"
-                    },
-                    "503": {
-                        "description": "Service Unavailable"
+                    "401": {
+                        "description": "Unauthorized"
                     }
                 }
             }
         },
-        "/account/logout": {
+        "/account/email/confirm": {
             "get": {
-                "description": "Logout existing user",
+                "description": "Apply a requested email change with the token mailed to the new address",
                 "consumes": [
                     "application/json"
                 ],
                 "tags": [
                     "account"
                 ],
-                "summary": "Logout",
+                "summary": "ConfirmEmail",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "email change token",
+                        "name": "token",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "400": {
+                        "description": "Bad Request"
                     }
                 }
             }
         },
-        "/account/register": {
+        "/account/forgot": {
             "post": {
-                "description": "Register new user",
+                "description": "Mail a password reset link, responding the same whether the account exists or not",
                 "consumes": [
                     "application/json"
                 ],
                 "tags": [
                     "account"
                 ],
-                "summary": "Register",
+                "summary": "Forgot",
                 "parameters": [
                     {
                         "description": "body",
@@ -119,7 +128,41 @@ const docTemplate = `{
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/management.RegisterRequest"
+                            "$ref": "#/definitions/management.ForgotRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    }
+                }
+            }
+        },
+        "/account/login": {
+            "post": {
+                "description": "Login existing user, answering with a challenge when the account has a second factor",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "Login",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/management.Request"
                         }
                     }
                 ],
@@ -130,9 +173,18 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/management.Challenge"
+                        }
+                    },
                     "400": {
                         "description": "Bad Request"
                     },
+                    "429": {
+                        "description": "Too Many Requests"
+                    },
                     "500": {
                         "description": "Internal Server Error"
                     },
@@ -142,16 +194,16 @@ const docTemplate = `{
                 }
             }
         },
-        "/campaign/create": {
+        "/account/login/verify": {
             "post": {
-                "description": "CreateCampaign",
+                "description": "Complete a login challenge with a TOTP or recovery code",
                 "consumes": [
                     "application/json"
                 ],
                 "tags": [
-                    "campaigns"
+                    "account"
                 ],
-                "summary": "CreateCampaign",
+                "summary": "VerifyLogin",
                 "parameters": [
                     {
                         "description": "body",
@@ -159,7 +211,7 @@ const docTemplate = `{
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/management.CreateCampaignRequest"
+                            "$ref": "#/definitions/management.LoginVerifyRequest"
                         }
                     }
                 ],
@@ -169,49 +221,140 @@ const docTemplate = `{
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "429": {
+                        "description": "Too Many Requests"
+                    },
+                    "503": {
+                        "description": "Service Unavailable"
                     }
                 }
             }
         },
-        "/campaign/remove/{key}": {
-            "delete": {
-                "description": "RemoveCampaign",
+        "/account/logout": {
+            "post": {
+                "description": "Logout existing user and revoke the session",
                 "consumes": [
                     "application/json"
                 ],
                 "tags": [
-                    "campaigns"
+                    "account"
                 ],
-                "summary": "RemoveCampaign",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "key",
-                        "name": "key",
-                        "in": "path",
-                        "required": true
+                "summary": "Logout",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden"
                     }
+                }
+            }
+        },
+        "/account/members": {
+            "get": {
+                "description": "List accounts of the current organization",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
                 ],
+                "summary": "ListMembers",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/management.Member"
+                            }
                         }
+                    },
+                    "401": {
+                        "description": "Unauthorized"
                     }
                 }
             }
         },
-        "/campaign/update": {
+        "/account/oidc/callback": {
+            "get": {
+                "description": "Complete single sign-on, signing in the verified account with the verified email of the identity or, with two-factor authentication enabled, redirecting to complete a login challenge",
+                "tags": [
+                    "account"
+                ],
+                "summary": "OIDCCallback",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "authorization code",
+                        "name": "code",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "state",
+                        "name": "state",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "302": {
+                        "description": "Found"
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "403": {
+                        "description": "Forbidden"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "503": {
+                        "description": "Service Unavailable"
+                    }
+                }
+            }
+        },
+        "/account/oidc/login": {
+            "get": {
+                "description": "Redirect to the identity provider to sign in with single sign-on",
+                "tags": [
+                    "account"
+                ],
+                "summary": "OIDCLogin",
+                "responses": {
+                    "302": {
+                        "description": "Found"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "503": {
+                        "description": "Service Unavailable"
+                    }
+                }
+            }
+        },
+        "/account/password": {
             "patch": {
-                "description": "UpdateCampaign",
+                "description": "Change password of the current account, signing out its other sessions",
                 "consumes": [
                     "application/json"
                 ],
                 "tags": [
-                    "campaigns"
+                    "account"
                 ],
-                "summary": "UpdateCampaign",
+                "summary": "ChangePassword",
                 "parameters": [
                     {
                         "description": "body",
@@ -219,7 +362,7 @@ const docTemplate = `{
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/management.UpdateCampaignRequest"
+                            "$ref": "#/definitions/management.PasswordRequest"
                         }
                     }
                 ],
@@ -229,105 +372,1546 @@ const docTemplate = `{
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "401": {
+                        "description": "Unauthorized"
                     }
                 }
             }
         },
-        "/campaigns": {
+        "/account/profile": {
             "get": {
-                "description": "ListCampaigns",
+                "description": "Profile of the current account",
                 "consumes": [
                     "application/json"
                 ],
                 "tags": [
-                    "campaigns"
+                    "account"
                 ],
-                "summary": "ListCampaigns",
+                "summary": "Profile",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/management.Member"
                         }
+                    },
+                    "401": {
+                        "description": "Unauthorized"
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "management.CreateCampaignRequest": {
-            "type": "object",
-            "properties": {
-                "accept": {
-                    "type": "number"
-                },
-                "active": {
-                    "type": "boolean"
-                },
-                "certificates": {
-                    "type": "array",
-                    "items": {
-                        "type": "string"
-                    }
-                },
-                "courses": {
-                    "type": "array",
-                    "items": {
-                        "type": "string"
-                    }
-                },
-                "education": {
-                    "type": "array",
-                    "items": {
-                        "type": "string"
-                    }
-                },
-                "experience": {
-                    "type": "array",
-                    "items": {
-                        "type": "string"
-                    }
-                },
-                "finish": {
-                    "type": "string"
-                },
-                "languages": {
-                    "type": "array",
-                    "items": {
-                        "type": "string"
-                    }
-                },
-                "name": {
-                    "type": "string"
-                },
-                "reject": {
-                    "type": "number"
-                },
-                "skills": {
-                    "type": "array",
-                    "items": {
-                        "type": "string"
+        },
+        "/account/refresh": {
+            "post": {
+                "description": "Rotate the refresh token and issue a new access token",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "Refresh",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized"
                     }
-                },
-                "start": {
-                    "type": "string"
-                },
-                "wanted": {
-                    "type": "integer"
                 }
             }
         },
-        "management.RegisterRequest": {
-            "type": "object",
-            "properties": {
-                "company": {
-                    "type": "string",
-                    "example": "ey"
-                },
-                "email": {
-                    "type": "string",
+        "/account/register": {
+            "post": {
+                "description": "Register new user with a new company, or into an existing one with an invitation",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "Register",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/management.RegisterRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    },
+                    "503": {
+                        "description": "Service Unavailable"
+                    }
+                }
+            }
+        },
+        "/account/remove/{key}": {
+            "delete": {
+                "description": "Remove an account from the organization",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "RemoveMember",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "account key",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "401": {
+                        "description": "Unauthorized"
+                    },
+                    "403": {
+                        "description": "Forbidden"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    }
+                }
+            }
+        },
+        "/account/reset": {
+            "post": {
+                "description": "Set a new password with a reset token, signing out every session",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "Reset",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/management.ResetRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    }
+                }
+            }
+        },
+        "/account/revoke": {
+            "post": {
+                "description": "Revoke every session of the current account",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "RevokeSessions",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized"
+                    }
+                }
+            }
+        },
+        "/account/role": {
+            "patch": {
+                "description": "Change the role of an organization member",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "ChangeRole",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/management.RoleRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "401": {
+                        "description": "Unauthorized"
+                    },
+                    "403": {
+                        "description": "Forbidden"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    }
+                }
+            }
+        },
+        "/account/totp/confirm": {
+            "post": {
+                "description": "Enable the enrolled TOTP with a first code, returning the recovery codes once",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "ConfirmTOTP",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/management.CodeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/management.RecoveryCodes"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "401": {
+                        "description": "Unauthorized"
+                    }
+                }
+            }
+        },
+        "/account/totp/disable": {
+            "post": {
+                "description": "Remove the second factor of the current account, confirmed with its password and a code",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "DisableTOTP",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/management.DisableTOTPRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "401": {
+                        "description": "Unauthorized"
+                    }
+                }
+            }
+        },
+        "/account/totp/enroll": {
+            "post": {
+                "description": "Start enrolling a TOTP second factor for the current account, it applies once confirmed",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "EnrollTOTP",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/management.Enrollment"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "401": {
+                        "description": "Unauthorized"
+                    }
+                }
+            }
+        },
+        "/account/totp/recovery": {
+            "post": {
+                "description": "Replace the recovery codes of the current account, confirmed with a code",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "RegenerateRecovery",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/management.CodeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/management.RecoveryCodes"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "401": {
+                        "description": "Unauthorized"
+                    }
+                }
+            }
+        },
+        "/account/verify": {
+            "get": {
+                "description": "Confirm the email address of an account with a verification token sent to its current address",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "Verify",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "verification token",
+                        "name": "token",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    }
+                }
+            }
+        },
+        "/account/verify/resend": {
+            "post": {
+                "description": "Mail a new verification link to the current account",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "ResendVerification",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "401": {
+                        "description": "Unauthorized"
+                    }
+                }
+            }
+        },
+        "/apikey/create": {
+            "post": {
+                "description": "Create an API key for integrations, its token is only returned once",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "apikeys"
+                ],
+                "summary": "CreateAPIKey",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/management.APIKeyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/management.APIKeyResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "401": {
+                        "description": "Unauthorized"
+                    },
+                    "403": {
+                        "description": "Forbidden"
+                    }
+                }
+            }
+        },
+        "/apikey/revoke/{key}": {
+            "delete": {
+                "description": "Revoke an API key of the organization",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "apikeys"
+                ],
+                "summary": "RevokeAPIKey",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "api key",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "401": {
+                        "description": "Unauthorized"
+                    },
+                    "403": {
+                        "description": "Forbidden"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    }
+                }
+            }
+        },
+        "/apikeys": {
+            "get": {
+                "description": "List API keys of the organization",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "apikeys"
+                ],
+                "summary": "ListAPIKeys",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/management.APIKey"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized"
+                    },
+                    "403": {
+                        "description": "Forbidden"
+                    }
+                }
+            }
+        },
+        "/application/status": {
+            "patch": {
+                "description": "Change the status of an application",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "applications"
+                ],
+                "summary": "UpdateApplicationStatus",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/management.ApplicationStatusRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "401": {
+                        "description": "Unauthorized"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    }
+                }
+            }
+        },
+        "/application/submit/{campaign}": {
+            "post": {
+                "description": "Submit an application to an open campaign",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "applications"
+                ],
+                "summary": "SubmitApplication",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "campaign key",
+                        "name": "campaign",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "body",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/management.ApplyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    }
+                }
+            }
+        },
+        "/application/{key}": {
+            "get": {
+                "description": "Get a single application",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "applications"
+                ],
+                "summary": "GetApplication",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "application key",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/management.Application"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    }
+                }
+            }
+        },
+        "/applications/{campaign}": {
+            "get": {
+                "description": "List applications submitted to a campaign",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "applications"
+                ],
+                "summary": "ListApplications",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "campaign key",
+                        "name": "campaign",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/management.Application"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    }
+                }
+            }
+        },
+        "/campaign/create": {
+            "post": {
+                "description": "CreateCampaign",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "campaigns"
+                ],
+                "summary": "CreateCampaign",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/management.CreateCampaignRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/campaign/remove/{key}": {
+            "delete": {
+                "description": "RemoveCampaign",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "campaigns"
+                ],
+                "summary": "RemoveCampaign",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "key",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the campaign version being removed",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "412": {
+                        "description": "Precondition Failed"
+                    }
+                }
+            }
+        },
+        "/campaign/update": {
+            "patch": {
+                "description": "UpdateCampaign",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "campaigns"
+                ],
+                "summary": "UpdateCampaign",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/management.UpdateCampaignRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the campaign version being updated",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    },
+                    "412": {
+                        "description": "Precondition Failed"
+                    }
+                }
+            }
+        },
+        "/campaign/{key}": {
+            "get": {
+                "description": "Get a single campaign, its version is returned as ETag",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "campaigns"
+                ],
+                "summary": "GetCampaign",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "campaign key",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/management.Campaign"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "404": {
+                        "description": "Not Found"
+                    }
+                }
+            }
+        },
+        "/campaigns": {
+            "get": {
+                "description": "ListCampaigns",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "campaigns"
+                ],
+                "summary": "ListCampaigns",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/management.Campaign"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/invitation/create": {
+            "post": {
+                "description": "Invite an email address to join the organization",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "invitations"
+                ],
+                "summary": "CreateInvitation",
+                "parameters": [
+                    {
+                        "description": "body",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/management.InviteRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/management.InvitationResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "401": {
+                        "description": "Unauthorized"
+                    }
+                }
+            }
+        },
+        "/invitations": {
+            "get": {
+                "description": "List invitations of the organization",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "invitations"
+                ],
+                "summary": "ListInvitations",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/management.Invitation"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized"
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "management.APIKey": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "type": "string"
+                },
+                "expires": {
+                    "type": "string"
+                },
+                "key": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "secret": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "management.APIKeyRequest": {
+            "type": "object",
+            "properties": {
+                "expires": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "ATS"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "campaigns:read",
+                        "applications:read"
+                    ]
+                }
+            }
+        },
+        "management.APIKeyResponse": {
+            "type": "object",
+            "properties": {
+                "expires": {
+                    "type": "string"
+                },
+                "key": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "management.Application": {
+            "type": "object",
+            "properties": {
+                "campaign": {
+                    "type": "string"
+                },
+                "certificates": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "courses": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "created": {
+                    "type": "string"
+                },
+                "education": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "email": {
+                    "type": "string"
+                },
+                "experience": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "key": {
+                    "type": "string"
+                },
+                "languages": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                },
+                "organization": {
+                    "type": "string"
+                },
+                "score": {
+                    "$ref": "#/definitions/management.Score"
+                },
+                "skills": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                },
+                "updated": {
+                    "type": "string"
+                }
+            }
+        },
+        "management.ApplicationStatusRequest": {
+            "type": "object",
+            "properties": {
+                "key": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "accepted"
+                }
+            }
+        },
+        "management.ApplyRequest": {
+            "type": "object",
+            "properties": {
+                "certificates": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "courses": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "education": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "email": {
+                    "type": "string",
+                    "example": "john@mock.com"
+                },
+                "experience": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "languages": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "name": {
+                    "type": "string",
+                    "example": "John Doe"
+                },
+                "skills": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "management.Campaign": {
+            "type": "object",
+            "properties": {
+                "accept": {
+                    "type": "number"
+                },
+                "active": {
+                    "type": "boolean"
+                },
+                "certificates": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/management.Criterion"
+                    }
+                },
+                "courses": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/management.Criterion"
+                    }
+                },
+                "created": {
+                    "type": "string"
+                },
+                "education": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/management.Criterion"
+                    }
+                },
+                "experience": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/management.Criterion"
+                    }
+                },
+                "finish": {
+                    "type": "string"
+                },
+                "key": {
+                    "type": "string"
+                },
+                "languages": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/management.Criterion"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                },
+                "organization": {
+                    "type": "string"
+                },
+                "reject": {
+                    "type": "number"
+                },
+                "skills": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/management.Criterion"
+                    }
+                },
+                "start": {
+                    "type": "string"
+                },
+                "updated": {
+                    "type": "string"
+                },
+                "wanted": {
+                    "type": "integer"
+                },
+                "weights": {
+                    "$ref": "#/definitions/management.Weights"
+                }
+            }
+        },
+        "management.Challenge": {
+            "type": "object",
+            "properties": {
+                "expires": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "management.CodeRequest": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "123456"
+                }
+            }
+        },
+        "management.CreateCampaignRequest": {
+            "type": "object",
+            "properties": {
+                "accept": {
+                    "type": "number"
+                },
+                "active": {
+                    "type": "boolean"
+                },
+                "certificates": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/management.Criterion"
+                    }
+                },
+                "courses": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/management.Criterion"
+                    }
+                },
+                "education": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/management.Criterion"
+                    }
+                },
+                "experience": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/management.Criterion"
+                    }
+                },
+                "finish": {
+                    "type": "string"
+                },
+                "languages": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/management.Criterion"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                },
+                "reject": {
+                    "type": "number"
+                },
+                "skills": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/management.Criterion"
+                    }
+                },
+                "start": {
+                    "type": "string"
+                },
+                "wanted": {
+                    "type": "integer"
+                },
+                "weights": {
+                    "$ref": "#/definitions/management.Weights"
+                }
+            }
+        },
+        "management.Criterion": {
+            "type": "object",
+            "properties": {
+                "mandatory": {
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Go"
+                },
+                "weight": {
+                    "type": "number",
+                    "example": 1
+                }
+            }
+        },
+        "management.CriterionScore": {
+            "type": "object",
+            "properties": {
+                "matched": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "missing": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                },
+                "value": {
+                    "type": "number"
+                },
+                "weight": {
+                    "type": "number"
+                }
+            }
+        },
+        "management.DisableTOTPRequest": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "123456"
+                },
+                "password": {
+                    "type": "string",
+                    "example": "P@ssw0rd"
+                }
+            }
+        },
+        "management.EmailRequest": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "mike@example.com"
+                },
+                "password": {
+                    "type": "string",
+                    "example": "P@ssw0rd"
+                }
+            }
+        },
+        "management.Enrollment": {
+            "type": "object",
+            "properties": {
+                "secret": {
+                    "type": "string"
+                },
+                "uri": {
+                    "type": "string"
+                }
+            }
+        },
+        "management.ForgotRequest": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "mike@mock.com"
+                }
+            }
+        },
+        "management.Identity": {
+            "type": "object",
+            "properties": {
+                "account": {
+                    "type": "string"
+                },
+                "company": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "organization": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                }
+            }
+        },
+        "management.Invitation": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "expires": {
+                    "type": "string"
+                },
+                "key": {
+                    "type": "string"
+                },
+                "organization": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "secret": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "used": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "management.InvitationResponse": {
+            "type": "object",
+            "properties": {
+                "expires": {
+                    "type": "string"
+                },
+                "key": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "management.InviteRequest": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "anna@mock.com"
+                },
+                "role": {
+                    "type": "string",
+                    "example": "recruiter"
+                }
+            }
+        },
+        "management.LoginVerifyRequest": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "123456"
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "management.Member": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "key": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "totp": {
+                    "type": "boolean"
+                },
+                "verified": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "management.PasswordRequest": {
+            "type": "object",
+            "properties": {
+                "new": {
+                    "type": "string",
+                    "example": "N3wP@ssw0rd"
+                },
+                "old": {
+                    "type": "string",
+                    "example": "P@ssw0rd"
+                }
+            }
+        },
+        "management.RecoveryCodes": {
+            "type": "object",
+            "properties": {
+                "codes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "management.RegisterRequest": {
+            "type": "object",
+            "properties": {
+                "company": {
+                    "type": "string",
+                    "example": "ey"
+                },
+                "email": {
+                    "type": "string",
                     "example": "mike@mock.com"
                 },
+                "invitation": {
+                    "description": "Invitation is required to join an existing organization, Company is\nignored when it is given.",
+                    "type": "string"
+                },
                 "password": {
                     "type": "string",
                     "example": "P@ssw0rd"
@@ -347,6 +1931,52 @@ const docTemplate = `{
                 }
             }
         },
+        "management.ResetRequest": {
+            "type": "object",
+            "properties": {
+                "password": {
+                    "type": "string",
+                    "example": "N3wP@ssw0rd"
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "management.RoleRequest": {
+            "type": "object",
+            "properties": {
+                "key": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string",
+                    "example": "admin"
+                }
+            }
+        },
+        "management.Score": {
+            "type": "object",
+            "properties": {
+                "criteria": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/management.CriterionScore"
+                    }
+                },
+                "disqualified": {
+                    "description": "Disqualified is set when the candidate misses a mandatory criterion.",
+                    "type": "boolean"
+                },
+                "unweighted": {
+                    "description": "Unweighted is set when the campaign has requirements but none of them\ncarries weight, so the score cannot tell candidates apart.",
+                    "type": "boolean"
+                },
+                "value": {
+                    "type": "number"
+                }
+            }
+        },
         "management.UpdateCampaignRequest": {
             "type": "object",
             "properties": {
@@ -359,25 +1989,25 @@ const docTemplate = `{
                 "certificates": {
                     "type": "array",
                     "items": {
-                        "type": "string"
+                        "$ref": "#/definitions/management.Criterion"
                     }
                 },
                 "courses": {
                     "type": "array",
                     "items": {
-                        "type": "string"
+                        "$ref": "#/definitions/management.Criterion"
                     }
                 },
                 "education": {
                     "type": "array",
                     "items": {
-                        "type": "string"
+                        "$ref": "#/definitions/management.Criterion"
                     }
                 },
                 "experience": {
                     "type": "array",
                     "items": {
-                        "type": "string"
+                        "$ref": "#/definitions/management.Criterion"
                     }
                 },
                 "finish": {
@@ -389,7 +2019,7 @@ const docTemplate = `{
                 "languages": {
                     "type": "array",
                     "items": {
-                        "type": "string"
+                        "$ref": "#/definitions/management.Criterion"
                     }
                 },
                 "name": {
@@ -401,7 +2031,7 @@ const docTemplate = `{
                 "skills": {
                     "type": "array",
                     "items": {
-                        "type": "string"
+                        "$ref": "#/definitions/management.Criterion"
                     }
                 },
                 "start": {
@@ -409,6 +2039,32 @@ const docTemplate = `{
                 },
                 "wanted": {
                     "type": "integer"
+                },
+                "weights": {
+                    "$ref": "#/definitions/management.Weights"
+                }
+            }
+        },
+        "management.Weights": {
+            "type": "object",
+            "properties": {
+                "certificates": {
+                    "type": "number"
+                },
+                "courses": {
+                    "type": "number"
+                },
+                "education": {
+                    "type": "number"
+                },
+                "experience": {
+                    "type": "number"
+                },
+                "languages": {
+                    "type": "number"
+                },
+                "skills": {
+                    "type": "number"
                 }
             }
         }
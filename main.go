@@ -2,6 +2,7 @@ package main
 
 import (
 	"log"
+	"strings"
 
 	_ "example.com/docs"
 	"example.com/management"
@@ -27,17 +28,30 @@ func main() {
 		log.Fatal(err)
 	}
 
-	management.Migrate(storage, config)
+	if err = management.Migrate(storage, config); err != nil {
+		log.Fatal(err)
+	}
+
+	mailer, err := management.NewMailer(config)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	keyring, err := management.NewKeyring(config)
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	router := fiber.New()
 	router.Use(cors.New(cors.Config{
-		AllowOrigins:     "*",
-		AllowHeaders:     "Origin, Content-Type, Accept, Content-Length, Accept-Language, Accept-Encoding, Connection, Access-Control-Allow-Origin",
+		AllowOrigins:     strings.Join(config.Origins, ", "),
+		AllowHeaders:     "Origin, Content-Type, Accept, Content-Length, Accept-Language, Accept-Encoding, Connection, Authorization, If-Match, " + config.CSRFHeader,
+		ExposeHeaders:    "ETag",
 		AllowCredentials: true,
 		AllowMethods:     "GET, POST, HEAD, PUT, DELETE, PATCH, OPTIONS",
 	}))
 	router.Get("/swagger/*", swagger.HandlerDefault)
-	management.NewServer(storage, config).Chain(router.Group(""))
+	management.NewServer(management.NewElasticStorage(storage, config), mailer, keyring, config).Chain(router.Group(""))
 	router.Use(func(c *fiber.Ctx) error { return c.Status(fiber.StatusNotFound).Redirect("/swagger/index.html") })
 
 	if err = router.Listen(config.Listen); err != nil {
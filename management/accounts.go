@@ -0,0 +1,212 @@
+package management
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// @Summary Profile
+// @Schemes
+// @Description Profile of the current account
+// @Tags account
+// @Accept application/json
+// @Success 200 {object} Member
+// @Failure 401
+// @Router /account/profile [get]
+func (s *server) Profile(c *fiber.Ctx) error {
+	return c.JSON(principal(c).Account.Member())
+}
+
+// @Summary ListMembers
+// @Schemes
+// @Description List accounts of the current organization
+// @Tags account
+// @Accept application/json
+// @Success 200 {array} Member
+// @Failure 401
+// @Router /account/members [get]
+func (s *server) ListMembers(c *fiber.Ctx) error {
+	members := []Member{}
+	for _, account := range principal(c).Organization.Accounts {
+		members = append(members, account.Member())
+	}
+
+	return c.JSON(members)
+}
+
+// @Summary ChangePassword
+// @Schemes
+// @Description Change password of the current account, signing out its other sessions
+// @Tags account
+// @Accept application/json
+// @Param payload body PasswordRequest true "body"
+// @Success 200 {object} string
+// @Failure 400
+// @Failure 401
+// @Router /account/password [patch]
+func (s *server) ChangePassword(c *fiber.Ctx) error {
+	var request PasswordRequest
+	if err := json.Unmarshal(c.Body(), &request); err != nil {
+		return fiber.NewError(http.StatusBadRequest, err.Error())
+	}
+
+	principal := principal(c)
+	if err := bcrypt.CompareHashAndPassword(principal.Account.Password, []byte(request.Old)); err != nil {
+		return fiber.NewError(http.StatusBadRequest, "incorrect password")
+	}
+
+	password, err := bcrypt.GenerateFromPassword([]byte(request.New), 14)
+	if err != nil {
+		return err
+	}
+
+	organization, err := s.modifyAccount(c, principal.Organization, principal.Account.Key, func(account *Account) error {
+		account.Password = password
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = s.revokeAccount(c, principal.Account.Key); err != nil {
+		return err
+	}
+
+	account, _ := organization.AccountByKey(principal.Account.Key)
+	if err = s.login(c, organization, account); err != nil {
+		return err
+	}
+
+	return c.SendString("password changed")
+}
+
+// @Summary ChangeEmail
+// @Schemes
+// @Description Request an email change of the current account, confirmed with its password and applied once the link mailed to the new address is used
+// @Tags account
+// @Accept application/json
+// @Param payload body EmailRequest true "body"
+// @Success 200 {object} string
+// @Failure 400
+// @Failure 401
+// @Router /account/email [patch]
+func (s *server) ChangeEmail(c *fiber.Ctx) error {
+	var request EmailRequest
+	if err := json.Unmarshal(c.Body(), &request); err != nil {
+		return fiber.NewError(http.StatusBadRequest, err.Error())
+	}
+
+	if request.Email == "" {
+		return fiber.NewError(http.StatusBadRequest, "email is required")
+	}
+
+	principal := principal(c)
+	if err := bcrypt.CompareHashAndPassword(principal.Account.Password, []byte(request.Password)); err != nil {
+		return fiber.NewError(http.StatusBadRequest, "incorrect password")
+	}
+
+	if err := s.available(c, request.Email.String(), principal.Account.Key); err != nil {
+		return err
+	}
+
+	// The ticket keeps the new address, the account keeps the current one
+	// until the link is used.
+	pending := principal.Account
+	pending.Email = request.Email.String()
+	lifetime := time.Duration(s.configuration.Verification) * time.Hour
+	token, err := s.ticket(c, PurposeEmail, principal.Organization, pending, lifetime)
+	if err != nil {
+		return err
+	}
+
+	err = s.mailer.Send(c.UserContext(), Message{
+		To:      pending.Email,
+		Subject: "Confirm your new email",
+		Body: fmt.Sprintf("Use the link below to confirm your new email address, it expires in %d hours.\n\n%s/account/email/confirm?token=%s",
+			s.configuration.Verification, s.configuration.URL, url.QueryEscape(token)),
+	})
+	if err != nil {
+		return fiber.NewError(http.StatusServiceUnavailable, err.Error())
+	}
+
+	err = s.mailer.Send(c.UserContext(), Message{
+		To:      principal.Account.Email,
+		Subject: "Email change requested",
+		Body:    fmt.Sprintf("A change of your email address to %s was requested, it applies once confirmed from that address. If it was not you, change your password.", pending.Email),
+	})
+	if err != nil {
+		return fiber.NewError(http.StatusServiceUnavailable, err.Error())
+	}
+
+	return c.SendString("check the inbox of the new address to confirm it")
+}
+
+// @Summary ConfirmEmail
+// @Schemes
+// @Description Apply a requested email change with the token mailed to the new address
+// @Tags account
+// @Accept application/json
+// @Param token query string true "email change token"
+// @Success 200 {object} string
+// @Failure 400
+// @Router /account/email/confirm [get]
+func (s *server) ConfirmEmail(c *fiber.Ctx) error {
+	ticket, err := s.findTicket(c, c.Query("token"), PurposeEmail)
+	if err != nil {
+		return err
+	}
+
+	if err = s.available(c, ticket.Email, ticket.Account); err != nil {
+		return err
+	}
+
+	if err = s.useTicket(c, ticket); err != nil {
+		return err
+	}
+
+	organization, err := s.organizations.FindByKey(c.UserContext(), ticket.Organization)
+	if err != nil {
+		return storageError(err)
+	}
+
+	_, err = s.modifyAccount(c, organization, ticket.Account, func(account *Account) error {
+		account.Email = ticket.Email
+		account.Verified = true
+		return nil
+	})
+	if errors.Is(err, errNoAccount) {
+		return fiber.NewError(http.StatusBadRequest, "invalid token")
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return c.SendString("email changed")
+}
+
+// available fails unless email is free or already used by the account.
+func (s *server) available(c *fiber.Ctx, email string, account uuid.UUID) error {
+	organization, err := s.organizations.FindByEmail(c.UserContext(), email)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+
+	if err != nil {
+		return storageError(err)
+	}
+
+	if existing, _ := organization.AccountByEmail(email); existing.Key == account {
+		return nil
+	}
+
+	return fiber.NewError(http.StatusBadRequest, "user with this email already exists")
+}
@@ -0,0 +1,80 @@
+package management
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestChangeEmail(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.register(t, "owner@example.com", "Acme")
+	s.register(t, "taken@example.com", "Other")
+
+	owner.expect(http.StatusBadRequest, "PATCH", "/account/email", `{"email":"new@example.com","password":"wrong1"}`)
+	owner.expect(http.StatusBadRequest, "PATCH", "/account/email", `{"email":"taken@example.com","password":"secret1"}`)
+	owner.expect(http.StatusOK, "PATCH", "/account/email", `{"email":"New@example.com","password":"secret1"}`)
+
+	// the address only changes once confirmed from the new inbox
+	var identity Identity
+	decode(t, owner.expect(http.StatusOK, "GET", "/account/authorize", ""), &identity)
+	if identity.Email != "owner@example.com" {
+		t.Fatalf("email changed before confirmation to %s", identity.Email)
+	}
+
+	if notice := s.mailer.last(t, "owner@example.com"); notice.Subject != "Email change requested" {
+		t.Fatalf("current address not notified, last message %+v", notice)
+	}
+
+	link := s.link(t, s.mailer.last(t, "new@example.com"), "/account/email/confirm?token=")
+	token := url.QueryEscape(s.mailer.last(t, "new@example.com").token(t))
+	s.client(t).expect(http.StatusBadRequest, "GET", "/account/verify?token="+token, "")
+	s.client(t).expect(http.StatusOK, "GET", link, "")
+	s.client(t).expect(http.StatusBadRequest, "GET", link, "")
+
+	var profile Member
+	decode(t, owner.expect(http.StatusOK, "GET", "/account/profile", ""), &profile)
+	if profile.Email != "new@example.com" || !profile.Verified {
+		t.Fatalf("email not confirmed %+v", profile)
+	}
+
+	s.client(t).expect(http.StatusBadRequest, "POST", "/account/login", `{"email":"owner@example.com","password":"secret1"}`)
+	s.client(t).expect(http.StatusOK, "POST", "/account/login", `{"email":"new@example.com","password":"secret1"}`)
+}
+
+func TestChangeEmailTakenMeanwhile(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.register(t, "owner@example.com", "Acme")
+	owner.expect(http.StatusOK, "PATCH", "/account/email", `{"email":"new@example.com","password":"secret1"}`)
+	link := s.link(t, s.mailer.last(t, "new@example.com"), "/account/email/confirm?token=")
+
+	s.register(t, "new@example.com", "Other")
+	s.client(t).expect(http.StatusBadRequest, "GET", link, "")
+}
+
+func TestVerifyPreviousAddress(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.register(t, "owner@example.com", "Acme")
+	verify := url.QueryEscape(s.mailer.last(t, "owner@example.com").token(t))
+
+	owner.expect(http.StatusOK, "PATCH", "/account/email", `{"email":"new@example.com","password":"secret1"}`)
+	s.client(t).expect(http.StatusOK, "GET", s.link(t, s.mailer.last(t, "new@example.com"), "/account/email/confirm?token="), "")
+
+	s.client(t).expect(http.StatusBadRequest, "GET", "/account/verify?token="+verify, "")
+}
+
+func TestVerify(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.register(t, "owner@example.com", "Acme")
+	link := s.link(t, s.mailer.last(t, "owner@example.com"), "/account/verify?token=")
+
+	s.client(t).expect(http.StatusBadRequest, "GET", "/account/verify?token=invalid", "")
+	s.client(t).expect(http.StatusOK, "GET", link, "")
+	s.client(t).expect(http.StatusBadRequest, "GET", link, "")
+
+	var profile Member
+	decode(t, owner.expect(http.StatusOK, "GET", "/account/profile", ""), &profile)
+	if !profile.Verified {
+		t.Fatal("account not verified")
+	}
+}
@@ -0,0 +1,122 @@
+package management
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// @Summary CreateAPIKey
+// @Schemes
+// @Description Create an API key for integrations, its token is only returned once
+// @Tags apikeys
+// @Accept application/json
+// @Param payload body APIKeyRequest true "body"
+// @Success 200 {object} APIKeyResponse
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Router /apikey/create [post]
+func (s *server) CreateAPIKey(c *fiber.Ctx) error {
+	var request APIKeyRequest
+	if err := json.Unmarshal(c.Body(), &request); err != nil {
+		return fiber.NewError(http.StatusBadRequest, err.Error())
+	}
+
+	if request.Name == "" || len(request.Scopes) == 0 {
+		return fiber.NewError(http.StatusBadRequest, "name and scopes are required")
+	}
+
+	if !request.Expires.IsZero() && request.Expires.Before(time.Now()) {
+		return fiber.NewError(http.StatusBadRequest, "expires should be in the future")
+	}
+
+	if !principal(c).Account.Verified {
+		return fiber.NewError(http.StatusForbidden, "verify your email before creating api keys")
+	}
+
+	token, digest, err := secret()
+	if err != nil {
+		return err
+	}
+
+	key := APIKey{
+		Key:     uuid.New(),
+		Name:    request.Name,
+		Secret:  digest,
+		Scopes:  request.Scopes,
+		Expires: request.Expires,
+		Created: time.Now(),
+	}
+
+	_, err = s.modify(c, principal(c).Organization, func(organization *Organization) error {
+		organization.Keys = append(organization.Keys, key)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(APIKeyResponse{
+		Key:     key.Key,
+		Token:   key.Key.String() + "." + token,
+		Expires: key.Expires,
+	})
+}
+
+// @Summary ListAPIKeys
+// @Schemes
+// @Description List API keys of the organization
+// @Tags apikeys
+// @Accept application/json
+// @Success 200 {array} APIKey
+// @Failure 401
+// @Failure 403
+// @Router /apikeys [get]
+func (s *server) ListAPIKeys(c *fiber.Ctx) error {
+	keys := []APIKey{}
+	for _, key := range principal(c).Organization.Keys {
+		key.Secret = nil
+		keys = append(keys, key)
+	}
+
+	return c.JSON(keys)
+}
+
+// @Summary RevokeAPIKey
+// @Schemes
+// @Description Revoke an API key of the organization
+// @Tags apikeys
+// @Accept application/json
+// @Param key path string true "api key"
+// @Success 200 {object} string
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Router /apikey/revoke/{key} [delete]
+func (s *server) RevokeAPIKey(c *fiber.Ctx) error {
+	key, err := uuid.Parse(c.Params("key"))
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, "invalid api key")
+	}
+
+	_, err = s.modify(c, principal(c).Organization, func(organization *Organization) error {
+		for i, item := range organization.Keys {
+			if item.Key == key {
+				organization.Keys = append(organization.Keys[:i], organization.Keys[i+1:]...)
+				return nil
+			}
+		}
+
+		return fiber.NewError(http.StatusNotFound, "api key with this id not found")
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.SendString("api key revoked")
+}
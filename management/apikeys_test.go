@@ -0,0 +1,140 @@
+package management
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// bearer sends a request authenticated with the Authorization header only.
+func (s *testServer) bearer(t *testing.T, authorization, method, path, body string) int {
+	t.Helper()
+	request := httptest.NewRequest(method, path, strings.NewReader(body))
+	request.Header.Set(fiber.HeaderAuthorization, authorization)
+
+	response, _ := s.client(t).send(request)
+	return response.StatusCode
+}
+
+func TestAPIKey(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.register(t, "owner@example.com", "Acme")
+
+	owner.expect(http.StatusForbidden, "POST", "/apikey/create", `{"name":"ATS","scopes":["campaigns:read"]}`)
+	s.verify(t, "owner@example.com")
+	owner.expect(http.StatusBadRequest, "POST", "/apikey/create", `{"name":"ATS","scopes":["campaigns:admin"]}`)
+	owner.expect(http.StatusBadRequest, "POST", "/apikey/create", `{"name":"ATS","scopes":[]}`)
+	owner.expect(http.StatusBadRequest, "POST", "/apikey/create", `{"name":"ATS","scopes":["campaigns:read"],"expires":"2020-01-01T00:00:00Z"}`)
+
+	var key APIKeyResponse
+	decode(t, owner.expect(http.StatusOK, "POST", "/apikey/create", `{"name":"ATS","scopes":["campaigns:read","applications:write"]}`), &key)
+	owner.expect(http.StatusOK, "POST", "/campaign/create", `{"name":"Backend"}`)
+
+	var campaigns []Campaign
+	decode(t, owner.expect(http.StatusOK, "GET", "/campaigns", ""), &campaigns)
+	campaign := campaigns[0].Key.String()
+
+	other := s.register(t, "other@example.com", "Other")
+	s.verify(t, "other@example.com")
+	other.expect(http.StatusOK, "POST", "/campaign/create", `{"name":"Hidden"}`)
+	decode(t, other.expect(http.StatusOK, "GET", "/campaigns", ""), &campaigns)
+	hidden := campaigns[0].Key.String()
+
+	authorization := "Bearer " + key.Token
+	requests := []struct {
+		method, path, body string
+		status             int
+	}{
+		{"GET", "/campaigns", "", http.StatusOK},
+		{"GET", "/campaign/" + campaign, "", http.StatusOK},
+		{"GET", "/campaign/" + hidden, "", http.StatusNotFound},
+		{"POST", "/campaign/create", `{"name":"Frontend"}`, http.StatusForbidden},
+		{"GET", "/applications/" + campaign, "", http.StatusForbidden},
+		{"PATCH", "/application/status", `{"key":"` + campaign + `","status":"review"}`, http.StatusNotFound},
+		{"GET", "/account/authorize", "", http.StatusUnauthorized},
+		{"GET", "/apikeys", "", http.StatusUnauthorized},
+	}
+
+	for _, request := range requests {
+		if status := s.bearer(t, authorization, request.method, request.path, request.body); status != request.status {
+			t.Errorf("%s %s: got %d, want %d", request.method, request.path, status, request.status)
+		}
+	}
+
+	var keys []APIKey
+	decode(t, owner.expect(http.StatusOK, "GET", "/apikeys", ""), &keys)
+	if len(keys) != 1 || keys[0].Secret != nil || keys[0].Name != "ATS" {
+		t.Fatalf("unexpected keys %+v", keys)
+	}
+
+	owner.expect(http.StatusOK, "DELETE", "/apikey/revoke/"+key.Key.String(), "")
+	owner.expect(http.StatusNotFound, "DELETE", "/apikey/revoke/"+key.Key.String(), "")
+	if status := s.bearer(t, authorization, "GET", "/campaigns", ""); status != http.StatusUnauthorized {
+		t.Fatalf("revoked key got %d", status)
+	}
+}
+
+func TestAPIKeyBearer(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.register(t, "owner@example.com", "Acme")
+	s.verify(t, "owner@example.com")
+
+	var key APIKeyResponse
+	decode(t, owner.expect(http.StatusOK, "POST", "/apikey/create", `{"name":"ATS","scopes":["campaigns:read"]}`), &key)
+	id, _, _ := split(key.Token)
+
+	for _, authorization := range []string{
+		key.Token,
+		"Basic " + key.Token,
+		"Bearer",
+		"Bearer ",
+		"Bearer invalid",
+		"Bearer " + id.String(),
+		"Bearer " + id.String() + ".secret",
+		"Bearer " + key.Token + "x",
+		"Bearer  " + key.Token,
+	} {
+		if status := s.bearer(t, authorization, "GET", "/campaigns", ""); status != http.StatusUnauthorized {
+			t.Errorf("%q: got %d, want 401", authorization, status)
+		}
+	}
+
+	if status := s.bearer(t, "Bearer "+key.Token, "GET", "/campaigns", ""); status != http.StatusOK {
+		t.Fatalf("got %d, want 200", status)
+	}
+}
+
+func TestAPIKeyExpired(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.register(t, "owner@example.com", "Acme")
+	s.verify(t, "owner@example.com")
+
+	var key APIKeyResponse
+	expires := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	decode(t, owner.expect(http.StatusOK, "POST", "/apikey/create", `{"name":"ATS","scopes":["campaigns:read"],"expires":"`+expires+`"}`), &key)
+
+	authorization := "Bearer " + key.Token
+	if status := s.bearer(t, authorization, "GET", "/campaigns", ""); status != http.StatusOK {
+		t.Fatalf("got %d, want 200", status)
+	}
+
+	ctx := context.Background()
+	organization, err := s.storage.Organizations.FindByAPIKey(ctx, key.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	organization.Keys[0].Expires = time.Now().Add(-time.Second)
+	if _, err = s.storage.Organizations.Save(ctx, organization); err != nil {
+		t.Fatal(err)
+	}
+
+	if status := s.bearer(t, authorization, "GET", "/campaigns", ""); status != http.StatusUnauthorized {
+		t.Fatalf("expired key got %d", status)
+	}
+}
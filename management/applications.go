@@ -0,0 +1,193 @@
+package management
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+var errApplied = fiber.NewError(http.StatusBadRequest, "application with this email already exists")
+
+// @Summary SubmitApplication
+// @Schemes
+// @Description Submit an application to an open campaign
+// @Tags applications
+// @Accept application/json
+// @Param campaign path string true "campaign key"
+// @Param payload body ApplyRequest true "body"
+// @Success 200 {object} string
+// @Failure 400
+// @Failure 404
+// @Router /application/submit/{campaign} [post]
+func (s *server) SubmitApplication(c *fiber.Ctx) error {
+	key, err := uuid.Parse(c.Params("campaign"))
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, "invalid campaign key")
+	}
+
+	var request ApplyRequest
+	if err := json.Unmarshal(c.Body(), &request); err != nil {
+		return fiber.NewError(http.StatusBadRequest, err.Error())
+	}
+
+	if strings.TrimSpace(request.Name) == "" || request.Email == "" {
+		return fiber.NewError(http.StatusBadRequest, "name and email are required")
+	}
+
+	campaign, err := s.campaigns.FindByKey(c.UserContext(), key)
+	if err != nil {
+		return storageError(err)
+	}
+
+	if !campaign.Open(time.Now()) {
+		return fiber.NewError(http.StatusBadRequest, "campaign is not accepting applications")
+	}
+
+	_, err = s.applications.FindByEmail(c.UserContext(), key, request.Email.String())
+	if err == nil {
+		return errApplied
+	}
+
+	if !errors.Is(err, ErrNotFound) {
+		return storageError(err)
+	}
+
+	// The key is derived from the campaign and the email, so concurrent
+	// submissions of the same candidate conflict when created.
+	application := Application{
+		Key:          uuid.NewSHA1(campaign.Key, []byte(request.Email.String())),
+		Organization: campaign.Organization,
+		Campaign:     campaign.Key,
+		Name:         request.Name,
+		Email:        request.Email.String(),
+		Education:    request.Education,
+		Experience:   request.Experience,
+		Certificates: request.Certificates,
+		Courses:      request.Courses,
+		Skills:       request.Skills,
+		Languages:    request.Languages,
+		Created:      time.Now(),
+	}
+
+	score := Evaluate(campaign, application)
+	application.Score = &score
+	application.Status = score.Decide(campaign)
+
+	_, err = s.applications.Save(c.UserContext(), application)
+	if errors.Is(err, ErrConflict) {
+		return errApplied
+	}
+
+	if err != nil {
+		return storageError(err)
+	}
+
+	return c.SendString("application submitted")
+}
+
+// @Summary ListApplications
+// @Schemes
+// @Description List applications submitted to a campaign
+// @Tags applications
+// @Accept application/json
+// @Param campaign path string true "campaign key"
+// @Success 200 {array} Application
+// @Failure 401
+// @Failure 404
+// @Router /applications/{campaign} [get]
+func (s *server) ListApplications(c *fiber.Ctx) error {
+	campaign, err := s.campaign(c, c.Params("campaign"))
+	if err != nil {
+		return err
+	}
+
+	applications, err := s.applications.ListByCampaign(c.UserContext(), campaign.Key)
+	if err != nil {
+		return storageError(err)
+	}
+
+	return c.JSON(applications)
+}
+
+// @Summary GetApplication
+// @Schemes
+// @Description Get a single application
+// @Tags applications
+// @Accept application/json
+// @Param key path string true "application key"
+// @Success 200 {object} Application
+// @Failure 401
+// @Failure 404
+// @Router /application/{key} [get]
+func (s *server) GetApplication(c *fiber.Ctx) error {
+	organization := principal(c).Organization
+
+	application, err := s.application(c, organization, c.Params("key"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(application)
+}
+
+// @Summary UpdateApplicationStatus
+// @Schemes
+// @Description Change the status of an application
+// @Tags applications
+// @Accept application/json
+// @Param payload body ApplicationStatusRequest true "body"
+// @Success 200 {object} string
+// @Failure 400
+// @Failure 401
+// @Failure 404
+// @Router /application/status [patch]
+func (s *server) UpdateApplicationStatus(c *fiber.Ctx) error {
+	var request ApplicationStatusRequest
+	if err := json.Unmarshal(c.Body(), &request); err != nil {
+		return fiber.NewError(http.StatusBadRequest, err.Error())
+	}
+
+	if !request.Status.Valid() {
+		return fiber.NewError(http.StatusBadRequest, "status should be one of submitted, review, accepted, rejected")
+	}
+
+	organization := principal(c).Organization
+
+	application, err := s.application(c, organization, request.Key.String())
+	if err != nil {
+		return err
+	}
+
+	application.Status = request.Status
+	application.Updated = time.Now()
+
+	if _, err = s.applications.Save(c.UserContext(), application); err != nil {
+		return storageError(err)
+	}
+
+	return c.SendString("application updated")
+}
+
+// application loads an application, hiding those of other organizations.
+func (s *server) application(c *fiber.Ctx, organization Organization, value string) (Application, error) {
+	key, err := uuid.Parse(value)
+	if err != nil {
+		return Application{}, fiber.NewError(http.StatusBadRequest, "invalid application key")
+	}
+
+	application, err := s.applications.FindByKey(c.UserContext(), key)
+	if err != nil {
+		return Application{}, storageError(err)
+	}
+
+	if application.Organization != organization.Key {
+		return Application{}, fiber.NewError(http.StatusNotFound, "application with this id not found")
+	}
+
+	return application, nil
+}
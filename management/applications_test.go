@@ -0,0 +1,124 @@
+package management
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+func TestSubmitApplication(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.register(t, "owner@example.com", "Acme")
+	s.verify(t, "owner@example.com")
+
+	owner.expect(http.StatusOK, "POST", "/campaign/create", `{"name":"Backend","active":true,"accept":0.5,"skills":["Go","SQL"]}`)
+	owner.expect(http.StatusOK, "POST", "/campaign/create", `{"name":"Closed","skills":["Go"]}`)
+
+	var campaigns []Campaign
+	decode(t, owner.expect(http.StatusOK, "GET", "/campaigns", ""), &campaigns)
+	open, closed := campaigns[0].Key.String(), campaigns[1].Key.String()
+
+	public := s.client(t)
+	public.expect(http.StatusOK, "POST", "/application/submit/"+open, `{"name":"Anna","email":"anna@example.com","skills":["go","sql"]}`)
+	public.expect(http.StatusBadRequest, "POST", "/application/submit/"+open, `{"name":"Anna","email":"ANNA@example.com","skills":["Go"]}`)
+	public.expect(http.StatusOK, "POST", "/application/submit/"+open, `{"name":"John","email":"john@example.com","skills":["Java"]}`)
+	public.expect(http.StatusBadRequest, "POST", "/application/submit/"+closed, `{"name":"Anna","email":"anna@example.com"}`)
+	public.expect(http.StatusNotFound, "POST", "/application/submit/00000000-0000-0000-0000-000000000000", `{"name":"Anna","email":"anna@example.com"}`)
+	public.expect(http.StatusBadRequest, "POST", "/application/submit/"+open, `{"name":" ","email":"mark@example.com"}`)
+
+	var applications []Application
+	decode(t, owner.expect(http.StatusOK, "GET", "/applications/"+open, ""), &applications)
+	if len(applications) != 2 {
+		t.Fatalf("got %d applications, want 2", len(applications))
+	}
+
+	statuses := map[string]Status{}
+	for _, application := range applications {
+		statuses[application.Email] = application.Status
+	}
+
+	if statuses["anna@example.com"] != StatusAccepted || statuses["john@example.com"] != StatusReview {
+		t.Fatalf("unexpected statuses %v", statuses)
+	}
+}
+
+func TestSubmitApplicationConcurrently(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.register(t, "owner@example.com", "Acme")
+	s.verify(t, "owner@example.com")
+	owner.expect(http.StatusOK, "POST", "/campaign/create", `{"name":"Backend","active":true}`)
+
+	var campaigns []Campaign
+	decode(t, owner.expect(http.StatusOK, "GET", "/campaigns", ""), &campaigns)
+
+	var wait sync.WaitGroup
+	codes := make([]int, 8)
+	for i := range codes {
+		wait.Add(1)
+		go func(i int) {
+			defer wait.Done()
+			codes[i], _ = s.client(t).do("POST", "/application/submit/"+campaigns[0].Key.String(), `{"name":"Anna","email":"anna@example.com"}`)
+		}(i)
+	}
+	wait.Wait()
+
+	accepted := 0
+	for _, code := range codes {
+		if code == http.StatusOK {
+			accepted++
+		}
+	}
+
+	if accepted != 1 {
+		t.Fatalf("%d concurrent submissions accepted, want 1: %v", accepted, codes)
+	}
+}
+
+func TestUpdateApplicationStatus(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.register(t, "owner@example.com", "Acme")
+	s.verify(t, "owner@example.com")
+	owner.expect(http.StatusOK, "POST", "/campaign/create", `{"name":"Backend","active":true}`)
+
+	var campaigns []Campaign
+	decode(t, owner.expect(http.StatusOK, "GET", "/campaigns", ""), &campaigns)
+	campaign := campaigns[0].Key.String()
+	s.client(t).expect(http.StatusOK, "POST", "/application/submit/"+campaign, `{"name":"Anna","email":"anna@example.com"}`)
+
+	var applications []Application
+	decode(t, owner.expect(http.StatusOK, "GET", "/applications/"+campaign, ""), &applications)
+	key := applications[0].Key.String()
+
+	for _, body := range []string{
+		`{"key":"` + key + `"}`,
+		`{"key":"` + key + `","status":""}`,
+		`{"key":"` + key + `","status":"hired"}`,
+	} {
+		owner.expect(http.StatusBadRequest, "PATCH", "/application/status", body)
+	}
+
+	owner.expect(http.StatusOK, "PATCH", "/application/status", `{"key":"`+key+`","status":"rejected"}`)
+
+	var application Application
+	decode(t, owner.expect(http.StatusOK, "GET", "/application/"+key, ""), &application)
+	if application.Status != StatusRejected || application.Updated.IsZero() {
+		t.Fatalf("status not updated %+v", application)
+	}
+
+	// a document stored with an unknown status is still listed
+	stored, err := s.storage.Applications.FindByKey(context.Background(), application.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored.Status = "hired"
+	if _, err = s.storage.Applications.Save(context.Background(), stored); err != nil {
+		t.Fatal(err)
+	}
+
+	decode(t, owner.expect(http.StatusOK, "GET", "/applications/"+campaign, ""), &applications)
+	if len(applications) != 1 || applications[0].Status != "hired" {
+		t.Fatalf("unexpected applications %+v", applications)
+	}
+}
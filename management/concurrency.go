@@ -0,0 +1,99 @@
+package management
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// conflictRetries bounds how often a change is applied again to a document
+// that was written concurrently.
+const conflictRetries = 5
+
+var errNoAccount = fiber.NewError(http.StatusNotFound, "account with this id not found")
+
+// modify applies change to the organization and saves it. When a concurrent
+// write wins, change is applied again to a fresh copy, so edits that do not
+// conflict are merged instead of overwritten.
+func (s *server) modify(c *fiber.Ctx, organization Organization, change func(*Organization) error) (Organization, error) {
+	for attempt := 1; ; attempt++ {
+		if err := change(&organization); err != nil {
+			return Organization{}, err
+		}
+
+		version, err := s.organizations.Save(c.UserContext(), organization)
+		if err == nil {
+			organization.Version = version
+			return organization, nil
+		}
+
+		if !errors.Is(err, ErrConflict) || attempt == conflictRetries {
+			return Organization{}, storageError(err)
+		}
+
+		if organization, err = s.organizations.FindByKey(c.UserContext(), organization.Key); err != nil {
+			return Organization{}, storageError(err)
+		}
+	}
+}
+
+// modifyAccount applies change to an account of the organization like modify.
+func (s *server) modifyAccount(c *fiber.Ctx, organization Organization, key uuid.UUID, change func(*Account) error) (Organization, error) {
+	return s.modify(c, organization, func(organization *Organization) error {
+		index := member(*organization, key)
+		if index == -1 {
+			return errNoAccount
+		}
+
+		return change(&organization.Accounts[index])
+	})
+}
+
+// modifyCampaign applies change to the campaign like modify. A request sending
+// If-Match only applies to the version it names and fails with 412 otherwise.
+// The new version is returned as ETag.
+func (s *server) modifyCampaign(c *fiber.Ctx, campaign Campaign, change func(*Campaign) error) (Campaign, error) {
+	for attempt := 1; ; attempt++ {
+		if err := precondition(c, campaign.Version); err != nil {
+			return Campaign{}, err
+		}
+
+		if err := change(&campaign); err != nil {
+			return Campaign{}, err
+		}
+
+		version, err := s.campaigns.Save(c.UserContext(), campaign)
+		if err == nil {
+			campaign.Version = version
+			c.Set(fiber.HeaderETag, version.ETag())
+			return campaign, nil
+		}
+
+		if !errors.Is(err, ErrConflict) || attempt == conflictRetries {
+			return Campaign{}, storageError(err)
+		}
+
+		if campaign, err = s.campaign(c, campaign.Key.String()); err != nil {
+			return Campaign{}, err
+		}
+	}
+}
+
+// precondition rejects requests whose If-Match names another version.
+func precondition(c *fiber.Ctx, version Version) error {
+	match := c.Get(fiber.HeaderIfMatch)
+	if match == "" || match == "*" {
+		return nil
+	}
+
+	for _, tag := range strings.Split(match, ",") {
+		if strings.TrimSpace(tag) == version.ETag() {
+			return nil
+		}
+	}
+
+	return fiber.NewError(http.StatusPreconditionFailed, "resource was modified, reload it and try again")
+}
@@ -0,0 +1,131 @@
+package management
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// racingCampaigns lets another writer change a campaign right before the
+// first save of a request, so that save conflicts.
+type racingCampaigns struct {
+	CampaignRepository
+	race func(ctx context.Context, campaign Campaign)
+}
+
+func (r *racingCampaigns) Save(ctx context.Context, campaign Campaign) (Version, error) {
+	if race := r.race; race != nil {
+		r.race = nil
+		race(ctx, campaign)
+	}
+
+	return r.CampaignRepository.Save(ctx, campaign)
+}
+
+func (c *testClient) match(method, path, body, etag string) (int, string) {
+	c.t.Helper()
+	request := httptest.NewRequest(method, path, strings.NewReader(body))
+	request.Header.Set(fiber.HeaderIfMatch, etag)
+	response, data := c.send(request)
+	return response.StatusCode, data
+}
+
+// createCampaign creates a campaign and returns its key and ETag.
+func createCampaign(t *testing.T, client *testClient) (string, string) {
+	t.Helper()
+	client.expect(http.StatusOK, "POST", "/campaign/create", `{"name":"Backend"}`)
+
+	var campaigns []Campaign
+	decode(t, client.expect(http.StatusOK, "GET", "/campaigns", ""), &campaigns)
+	key := campaigns[0].Key.String()
+
+	response, _ := client.send(httptest.NewRequest("GET", "/campaign/"+key, nil))
+	etag := response.Header.Get(fiber.HeaderETag)
+	if etag == "" {
+		t.Fatal("campaign has no ETag")
+	}
+
+	return key, etag
+}
+
+func TestCampaignIfMatch(t *testing.T) {
+	s := newTestServer(t)
+	client := s.register(t, "owner@example.com", "Acme")
+	key, stale := createCampaign(t, client)
+
+	request := httptest.NewRequest("PATCH", "/campaign/update", strings.NewReader(`{"key":"`+key+`","name":"Frontend"}`))
+	request.Header.Set(fiber.HeaderIfMatch, stale)
+	response, _ := client.send(request)
+	current := response.Header.Get(fiber.HeaderETag)
+	if response.StatusCode != http.StatusOK || current == "" || current == stale {
+		t.Fatalf("got %d with ETag %q", response.StatusCode, current)
+	}
+
+	if status, _ := client.match("PATCH", "/campaign/update", `{"key":"`+key+`","name":"Stale"}`, stale); status != http.StatusPreconditionFailed {
+		t.Fatalf("stale update got %d, want 412", status)
+	}
+
+	if status, _ := client.match("DELETE", "/campaign/remove/"+key, "", stale); status != http.StatusPreconditionFailed {
+		t.Fatalf("stale removal got %d, want 412", status)
+	}
+
+	var campaign Campaign
+	decode(t, client.expect(http.StatusOK, "GET", "/campaign/"+key, ""), &campaign)
+	if campaign.Name != "Frontend" {
+		t.Fatalf("got name %q, want Frontend", campaign.Name)
+	}
+
+	if status, _ := client.match("DELETE", "/campaign/remove/"+key, "", stale+", "+current); status != http.StatusOK {
+		t.Fatalf("removal got %d, want 200", status)
+	}
+
+	client.expect(http.StatusNotFound, "GET", "/campaign/"+key, "")
+}
+
+func TestCampaignConflict(t *testing.T) {
+	campaigns := &racingCampaigns{CampaignRepository: NewMemoryStorage().Campaigns}
+	storage := NewMemoryStorage()
+	storage.Campaigns = campaigns
+	s := newStorageTestServer(t, storage)
+	client := s.register(t, "owner@example.com", "Acme")
+	key, etag := createCampaign(t, client)
+
+	race := func(wanted int) func(context.Context, Campaign) {
+		return func(ctx context.Context, campaign Campaign) {
+			concurrent, err := campaigns.FindByKey(ctx, campaign.Key)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			concurrent.Wanted = wanted
+			if _, err := campaigns.CampaignRepository.Save(ctx, concurrent); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// a request naming the version it read refuses to overwrite the change
+	campaigns.race = race(7)
+	if status, _ := client.match("PATCH", "/campaign/update", `{"key":"`+key+`","name":"Frontend"}`, etag); status != http.StatusPreconditionFailed {
+		t.Fatalf("got %d, want 412", status)
+	}
+
+	var campaign Campaign
+	decode(t, client.expect(http.StatusOK, "GET", "/campaign/"+key, ""), &campaign)
+	if campaign.Name != "Backend" || campaign.Wanted != 7 {
+		t.Fatalf("got %q wanting %d", campaign.Name, campaign.Wanted)
+	}
+
+	// without If-Match the change is applied again on top of the other one
+	campaigns.race = race(9)
+	client.expect(http.StatusOK, "PATCH", "/campaign/update", `{"key":"`+key+`","name":"Frontend"}`)
+
+	decode(t, client.expect(http.StatusOK, "GET", "/campaign/"+key, ""), &campaign)
+	if campaign.Name != "Frontend" || campaign.Wanted != 9 {
+		t.Fatalf("got %q wanting %d, want both changes", campaign.Name, campaign.Wanted)
+	}
+}
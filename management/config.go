@@ -1,16 +1,70 @@
 package management
 
 import (
+	"errors"
+	"strings"
+
 	_ "github.com/joho/godotenv/autoload"
 	"github.com/kelseyhightower/envconfig"
 )
 
+const ModeDevelopment = "development"
+
+// developmentSecret signs tokens in development mode when no SECRET is set.
+const developmentSecret = "yfasdhudashnjdas"
+
+// Config is read from the environment. URL is the origin of mailed links and
+// redirects: /account/verify and /account/email/confirm are served by the API,
+// while the frontend under the same origin has to serve /reset, /register and
+// /login/verify, which take the token from the query.
 type Config struct {
-	Listen     string `envconfig:"LISTEN" default:":5000"`
-	Secret     string `envconfig:"SECRET" default:"yfasdhudashnjdas"`
-	Index      string `envconfig:"INDEX" default:"organizations"`
-	Cookie     string `envconfig:"COOKIE" default:"cookie"`
-	Expiration int    `envconfig:"EXPIRATION" default:"2"`
+	Listen           string   `envconfig:"LISTEN" default:":5000"`
+	Mode             string   `envconfig:"MODE" default:"production"` // development allows the development secret
+	Secret           string   `envconfig:"SECRET"`
+	Secrets          []string `envconfig:"SECRETS"`           // previous secrets still accepted while tokens signed with them expire
+	SigningKey       string   `envconfig:"SIGNING_KEY"`       // PEM private key file, RSA for RS256 or Ed25519 for EdDSA, replacing Secret for signing
+	VerificationKeys []string `envconfig:"VERIFICATION_KEYS"` // PEM public key files of previous signing keys
+	Index            string   `envconfig:"INDEX" default:"organizations"`
+	Campaigns        string   `envconfig:"CAMPAIGNS" default:"campaigns"`
+	Applications     string   `envconfig:"APPLICATIONS" default:"applications"`
+	Sessions         string   `envconfig:"SESSIONS" default:"sessions"`
+	Revocations      string   `envconfig:"REVOCATIONS" default:"revocations"`
+	Invitations      string   `envconfig:"INVITATIONS" default:"invitations"`
+	Tickets          string   `envconfig:"TICKETS" default:"tickets"`
+	Migrations       string   `envconfig:"MIGRATIONS" default:"migrations"` // applied schema migrations and the migration lock
+	Issuer           string   `envconfig:"ISSUER" default:"example.com"`
+	Cookie           string   `envconfig:"COOKIE" default:"cookie"`
+	RefreshCookie    string   `envconfig:"REFRESH_COOKIE" default:"refresh"`
+	CSRFCookie       string   `envconfig:"CSRF_COOKIE" default:"csrf"`
+	CSRFHeader       string   `envconfig:"CSRF_HEADER" default:"X-CSRF-Token"`
+	CookieDomain     string   `envconfig:"COOKIE_DOMAIN"`
+	CookiePath       string   `envconfig:"COOKIE_PATH" default:"/"`
+	CookieSecure     bool     `envconfig:"COOKIE_SECURE" default:"true"`
+	CookieHTTPOnly   bool     `envconfig:"COOKIE_HTTP_ONLY" default:"true"`
+	CookieSameSite   string   `envconfig:"COOKIE_SAME_SITE" default:"lax"`          // lax, strict or none
+	Origins          []string `envconfig:"ORIGINS" default:"http://localhost:5000"` // origins allowed to call the API with credentials
+	Access           int      `envconfig:"ACCESS" default:"15"`                     // access token lifetime in minutes
+	Expiration       int      `envconfig:"EXPIRATION" default:"2"`                  // refresh token lifetime in hours
+	Invitation       int      `envconfig:"INVITATION" default:"72"`                 // invitation lifetime in hours
+	Reset            int      `envconfig:"RESET" default:"60"`                      // password reset lifetime in minutes
+	Verification     int      `envconfig:"VERIFICATION" default:"48"`               // email verification lifetime in hours
+	LoginAttempts    int      `envconfig:"LOGIN_ATTEMPTS" default:"5"`              // failed logins before an account is locked
+	LoginLockout     int      `envconfig:"LOGIN_LOCKOUT" default:"15"`              // account lockout in minutes
+	LoginBackoff     int      `envconfig:"LOGIN_BACKOFF" default:"1"`               // first login backoff in seconds, doubled on every failure
+	LoginIPAttempts  int      `envconfig:"LOGIN_IP_ATTEMPTS" default:"20"`          // failed logins from an address before backoff applies
+	Challenge        int      `envconfig:"CHALLENGE" default:"5"`                   // pending two-factor login lifetime in minutes
+	URL              string   `envconfig:"URL" default:"http://localhost:5000"`
+	MailFrom         string   `envconfig:"MAIL_FROM" default:"no-reply@example.com"`
+	MailFile         string   `envconfig:"MAIL_FILE"` // messages are appended to it without SMTP_HOST
+	SMTPHost         string   `envconfig:"SMTP_HOST"` // either SMTP_HOST or MAIL_FILE is required outside of development mode
+	SMTPPort         int      `envconfig:"SMTP_PORT" default:"587"`
+	SMTPUsername     string   `envconfig:"SMTP_USERNAME"`
+	SMTPPassword     string   `envconfig:"SMTP_PASSWORD"`
+	OIDCIssuer       string   `envconfig:"OIDC_ISSUER"` // single sign-on is disabled without an issuer
+	OIDCClient       string   `envconfig:"OIDC_CLIENT"`
+	OIDCSecret       string   `envconfig:"OIDC_SECRET"`
+	OIDCRedirect     string   `envconfig:"OIDC_REDIRECT" default:"http://localhost:5000/account/oidc/callback"`
+	OIDCScopes       string   `envconfig:"OIDC_SCOPES" default:"openid email profile"`
 }
 
 func NewConfig() (Config, error) {
@@ -20,5 +74,29 @@ func NewConfig() (Config, error) {
 		return config, err
 	}
 
+	if config.Secret == "" && config.Mode == ModeDevelopment {
+		config.Secret = developmentSecret
+	}
+
+	if config.Mode != ModeDevelopment && (config.Secret == developmentSecret || config.Secret == "" && config.SigningKey == "") {
+		return config, errors.New("SECRET or SIGNING_KEY has to be set outside of development mode")
+	}
+
+	switch strings.ToLower(config.CookieSameSite) {
+	case "lax", "strict":
+	case "none":
+		if !config.CookieSecure {
+			return config, errors.New("COOKIE_SAME_SITE none requires COOKIE_SECURE")
+		}
+	default:
+		return config, errors.New("COOKIE_SAME_SITE should be one of lax, strict, none")
+	}
+
+	for _, origin := range config.Origins {
+		if origin == "*" {
+			return config, errors.New("ORIGINS cannot allow every origin since requests carry credentials")
+		}
+	}
+
 	return config, nil
 }
@@ -0,0 +1,504 @@
+package management
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"example.com/query"
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/google/uuid"
+)
+
+func NewElasticStorage(s *elasticsearch.Client, c Config) Storage {
+	return Storage{
+		Organizations: &elasticOrganizations{documents: elasticDocuments{storage: s, index: c.Index}},
+		Campaigns:     &elasticCampaigns{documents: elasticDocuments{storage: s, index: c.Campaigns}},
+		Applications:  &elasticApplications{documents: elasticDocuments{storage: s, index: c.Applications}},
+		Sessions:      &elasticSessions{documents: elasticDocuments{storage: s, index: c.Sessions}},
+		Revocations:   &elasticRevocations{documents: elasticDocuments{storage: s, index: c.Revocations}},
+		Invitations:   &elasticInvitations{documents: elasticDocuments{storage: s, index: c.Invitations}},
+		Tickets:       &elasticTickets{documents: elasticDocuments{storage: s, index: c.Tickets}},
+	}
+}
+
+type elasticOrganizations struct {
+	documents elasticDocuments
+}
+
+func (r *elasticOrganizations) FindByEmail(ctx context.Context, email string) (Organization, error) {
+	return r.search(ctx, query.Search{Query: query.Nested("accounts", query.Term("accounts.email", strings.ToLower(email)))})
+}
+
+func (r *elasticOrganizations) FindByName(ctx context.Context, name string) (Organization, error) {
+	return r.search(ctx, query.Search{Query: query.Term("name", name)})
+}
+
+func (r *elasticOrganizations) FindByKey(ctx context.Context, key uuid.UUID) (Organization, error) {
+	var organization Organization
+	version, err := r.documents.get(ctx, key.String(), &organization)
+	if err != nil {
+		return Organization{}, err
+	}
+
+	organization.Version = version
+	return organization, nil
+}
+
+func (r *elasticOrganizations) FindByAPIKey(ctx context.Context, key uuid.UUID) (Organization, error) {
+	return r.search(ctx, query.Search{Query: query.Term("keys.key", key)})
+}
+
+func (r *elasticOrganizations) Save(ctx context.Context, organization Organization) (Version, error) {
+	return r.documents.save(ctx, organization.Key.String(), organization, organization.Version)
+}
+
+func (r *elasticOrganizations) search(ctx context.Context, search query.Search) (Organization, error) {
+	hits, err := r.documents.search(ctx, search, 1)
+	if err != nil {
+		return Organization{}, err
+	}
+
+	if len(hits) == 0 {
+		return Organization{}, ErrNotFound
+	}
+
+	var organization Organization
+	if err = json.Unmarshal(hits[0].Source, &organization); err != nil {
+		return Organization{}, err
+	}
+
+	organization.Version = hits[0].version()
+	return organization, nil
+}
+
+type elasticCampaigns struct {
+	documents elasticDocuments
+}
+
+func (r *elasticCampaigns) FindByKey(ctx context.Context, key uuid.UUID) (Campaign, error) {
+	var campaign Campaign
+	version, err := r.documents.get(ctx, key.String(), &campaign)
+	if err != nil {
+		return Campaign{}, err
+	}
+
+	campaign.Version = version
+	return campaign, nil
+}
+
+func (r *elasticCampaigns) ListByOrganization(ctx context.Context, organization uuid.UUID) ([]Campaign, error) {
+	hits, err := r.documents.search(ctx, query.Search{
+		Query: query.Term("organization", organization),
+		Sort:  []query.Sort{{Field: "created", Order: query.Asc}},
+	}, elasticMaxResults)
+	if err != nil {
+		return nil, err
+	}
+
+	campaigns := make([]Campaign, 0, len(hits))
+	for _, hit := range hits {
+		var campaign Campaign
+		if err = json.Unmarshal(hit.Source, &campaign); err != nil {
+			return nil, err
+		}
+
+		campaign.Version = hit.version()
+		campaigns = append(campaigns, campaign)
+	}
+
+	return campaigns, nil
+}
+
+func (r *elasticCampaigns) Save(ctx context.Context, campaign Campaign) (Version, error) {
+	return r.documents.save(ctx, campaign.Key.String(), campaign, campaign.Version)
+}
+
+func (r *elasticCampaigns) Remove(ctx context.Context, campaign Campaign) error {
+	return r.documents.remove(ctx, campaign.Key.String(), campaign.Version)
+}
+
+type elasticApplications struct {
+	documents elasticDocuments
+}
+
+func (r *elasticApplications) FindByKey(ctx context.Context, key uuid.UUID) (Application, error) {
+	var application Application
+	version, err := r.documents.get(ctx, key.String(), &application)
+	if err != nil {
+		return Application{}, err
+	}
+
+	application.Version = version
+	return application, nil
+}
+
+func (r *elasticApplications) FindByEmail(ctx context.Context, campaign uuid.UUID, email string) (Application, error) {
+	hits, err := r.documents.search(ctx, query.Search{
+		Query: query.Bool{Filter: []query.Query{
+			query.Term("campaign", campaign),
+			query.Term("email", strings.ToLower(email)),
+		}},
+	}, 1)
+	if err != nil {
+		return Application{}, err
+	}
+
+	if len(hits) == 0 {
+		return Application{}, ErrNotFound
+	}
+
+	var application Application
+	if err = json.Unmarshal(hits[0].Source, &application); err != nil {
+		return Application{}, err
+	}
+
+	application.Version = hits[0].version()
+	return application, nil
+}
+
+func (r *elasticApplications) ListByCampaign(ctx context.Context, campaign uuid.UUID) ([]Application, error) {
+	hits, err := r.documents.search(ctx, query.Search{
+		Query: query.Term("campaign", campaign),
+		Sort:  []query.Sort{{Field: "created", Order: query.Asc}},
+	}, elasticMaxResults)
+	if err != nil {
+		return nil, err
+	}
+
+	applications := make([]Application, 0, len(hits))
+	for _, hit := range hits {
+		var application Application
+		if err = json.Unmarshal(hit.Source, &application); err != nil {
+			return nil, err
+		}
+
+		application.Version = hit.version()
+		applications = append(applications, application)
+	}
+
+	return applications, nil
+}
+
+func (r *elasticApplications) Save(ctx context.Context, application Application) (Version, error) {
+	return r.documents.save(ctx, application.Key.String(), application, application.Version)
+}
+
+type elasticSessions struct {
+	documents elasticDocuments
+}
+
+func (r *elasticSessions) FindByKey(ctx context.Context, key uuid.UUID) (Session, error) {
+	var session Session
+	version, err := r.documents.get(ctx, key.String(), &session)
+	if err != nil {
+		return Session{}, err
+	}
+
+	session.Version = version
+	return session, nil
+}
+
+func (r *elasticSessions) ListByFamily(ctx context.Context, family uuid.UUID) ([]Session, error) {
+	return r.list(ctx, query.Search{Query: query.Term("family", family)})
+}
+
+func (r *elasticSessions) ListByAccount(ctx context.Context, account uuid.UUID) ([]Session, error) {
+	return r.list(ctx, query.Search{Query: query.Term("account", account)})
+}
+
+func (r *elasticSessions) Save(ctx context.Context, session Session) (Version, error) {
+	return r.documents.save(ctx, session.Key.String(), session, session.Version)
+}
+
+func (r *elasticSessions) list(ctx context.Context, search query.Search) ([]Session, error) {
+	hits, err := r.documents.search(ctx, search, elasticMaxResults)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]Session, 0, len(hits))
+	for _, hit := range hits {
+		var session Session
+		if err = json.Unmarshal(hit.Source, &session); err != nil {
+			return nil, err
+		}
+
+		session.Version = hit.version()
+		sessions = append(sessions, session)
+	}
+
+	return sessions, nil
+}
+
+type elasticRevocations struct {
+	documents elasticDocuments
+}
+
+func (r *elasticRevocations) Revoke(ctx context.Context, revocation Revocation) error {
+	_, err := r.documents.save(ctx, revocation.Token.String(), revocation, Version{})
+	if errors.Is(err, ErrConflict) {
+		return nil
+	}
+
+	return err
+}
+
+func (r *elasticRevocations) IsRevoked(ctx context.Context, token uuid.UUID) (bool, error) {
+	var revocation Revocation
+	if _, err := r.documents.get(ctx, token.String(), &revocation); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (r *elasticRevocations) Prune(ctx context.Context, now time.Time) error {
+	return r.documents.removeAll(ctx, query.Range{Field: "expires", Lt: now})
+}
+
+type elasticInvitations struct {
+	documents elasticDocuments
+}
+
+func (r *elasticInvitations) FindByKey(ctx context.Context, key uuid.UUID) (Invitation, error) {
+	var invitation Invitation
+	version, err := r.documents.get(ctx, key.String(), &invitation)
+	if err != nil {
+		return Invitation{}, err
+	}
+
+	invitation.Version = version
+	return invitation, nil
+}
+
+func (r *elasticInvitations) ListByOrganization(ctx context.Context, organization uuid.UUID) ([]Invitation, error) {
+	hits, err := r.documents.search(ctx, query.Search{
+		Query: query.Term("organization", organization),
+		Sort:  []query.Sort{{Field: "created", Order: query.Asc}},
+	}, elasticMaxResults)
+	if err != nil {
+		return nil, err
+	}
+
+	invitations := make([]Invitation, 0, len(hits))
+	for _, hit := range hits {
+		var invitation Invitation
+		if err = json.Unmarshal(hit.Source, &invitation); err != nil {
+			return nil, err
+		}
+
+		invitation.Version = hit.version()
+		invitations = append(invitations, invitation)
+	}
+
+	return invitations, nil
+}
+
+func (r *elasticInvitations) Save(ctx context.Context, invitation Invitation) (Version, error) {
+	return r.documents.save(ctx, invitation.Key.String(), invitation, invitation.Version)
+}
+
+type elasticTickets struct {
+	documents elasticDocuments
+}
+
+func (r *elasticTickets) FindByKey(ctx context.Context, key uuid.UUID) (Ticket, error) {
+	var ticket Ticket
+	version, err := r.documents.get(ctx, key.String(), &ticket)
+	if err != nil {
+		return Ticket{}, err
+	}
+
+	ticket.Version = version
+	return ticket, nil
+}
+
+func (r *elasticTickets) Save(ctx context.Context, ticket Ticket) (Version, error) {
+	return r.documents.save(ctx, ticket.Key.String(), ticket, ticket.Version)
+}
+
+// elasticMaxResults matches the default index.max_result_window.
+const elasticMaxResults = 10000
+
+// elasticDocuments implements the document operations shared by every
+// Elasticsearch backed repository.
+type elasticDocuments struct {
+	storage *elasticsearch.Client
+	index   string
+}
+
+type elasticHit struct {
+	SeqNo       int             `json:"_seq_no"`
+	PrimaryTerm int             `json:"_primary_term"`
+	Source      json.RawMessage `json:"_source"`
+}
+
+func (h elasticHit) version() Version {
+	return Version{SeqNo: h.SeqNo, PrimaryTerm: h.PrimaryTerm}
+}
+
+func (d elasticDocuments) get(ctx context.Context, id string, target interface{}) (Version, error) {
+	response, err := d.storage.Get(d.index, id, d.storage.Get.WithContext(ctx))
+	if err != nil {
+		return Version{}, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotFound {
+		return Version{}, ErrNotFound
+	}
+
+	if response.IsError() {
+		return Version{}, fmt.Errorf("elasticsearch: %s", response.String())
+	}
+
+	var hit elasticHit
+	if err = json.NewDecoder(response.Body).Decode(&hit); err != nil {
+		return Version{}, err
+	}
+
+	if err = json.Unmarshal(hit.Source, target); err != nil {
+		return Version{}, err
+	}
+
+	return hit.version(), nil
+}
+
+func (d elasticDocuments) search(ctx context.Context, search query.Search, size int) ([]elasticHit, error) {
+	body, err := json.Marshal(search)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := d.storage.Search(
+		d.storage.Search.WithContext(ctx),
+		d.storage.Search.WithIndex(d.index),
+		d.storage.Search.WithBody(bytes.NewReader(body)),
+		d.storage.Search.WithSize(size),
+		d.storage.Search.WithSeqNoPrimaryTerm(true),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.IsError() {
+		return nil, fmt.Errorf("elasticsearch: %s", response.String())
+	}
+
+	var payload struct {
+		Hits struct {
+			Hits []elasticHit `json:"hits"`
+		} `json:"hits"`
+	}
+
+	if err = json.NewDecoder(response.Body).Decode(&payload); err != nil {
+		return nil, err
+	}
+
+	return payload.Hits.Hits, nil
+}
+
+// save indexes the document only if it is still at version, creating it when
+// version is zero.
+func (d elasticDocuments) save(ctx context.Context, id string, document interface{}, version Version) (Version, error) {
+	data, err := json.Marshal(document)
+	if err != nil {
+		return Version{}, err
+	}
+
+	request := esapi.IndexRequest{
+		Index:      d.index,
+		DocumentID: id,
+		Body:       bytes.NewReader(data),
+	}
+
+	if version.IsZero() {
+		request.OpType = "create"
+	} else {
+		request.IfSeqNo = &version.SeqNo
+		request.IfPrimaryTerm = &version.PrimaryTerm
+	}
+
+	response, err := request.Do(ctx, d.storage)
+	if err != nil {
+		return Version{}, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusConflict {
+		return Version{}, ErrConflict
+	}
+
+	if response.IsError() {
+		return Version{}, fmt.Errorf("elasticsearch: %s", response.String())
+	}
+
+	var hit elasticHit
+	if err = json.NewDecoder(response.Body).Decode(&hit); err != nil {
+		return Version{}, err
+	}
+
+	return hit.version(), nil
+}
+
+// remove deletes the document only if it is still at version.
+func (d elasticDocuments) remove(ctx context.Context, id string, version Version) error {
+	request := esapi.DeleteRequest{
+		Index:         d.index,
+		DocumentID:    id,
+		IfSeqNo:       &version.SeqNo,
+		IfPrimaryTerm: &version.PrimaryTerm,
+	}
+
+	response, err := request.Do(ctx, d.storage)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	switch {
+	case response.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case response.StatusCode == http.StatusConflict:
+		return ErrConflict
+	case response.IsError():
+		return fmt.Errorf("elasticsearch: %s", response.String())
+	}
+
+	return nil
+}
+
+// removeAll deletes the documents matching q, skipping those changed while
+// it runs.
+func (d elasticDocuments) removeAll(ctx context.Context, q query.Query) error {
+	body, err := json.Marshal(query.Search{Query: q})
+	if err != nil {
+		return err
+	}
+
+	response, err := d.storage.DeleteByQuery([]string{d.index}, bytes.NewReader(body),
+		d.storage.DeleteByQuery.WithContext(ctx),
+		d.storage.DeleteByQuery.WithConflicts("proceed"),
+	)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.IsError() {
+		return fmt.Errorf("elasticsearch: %s", response.String())
+	}
+
+	return nil
+}
@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"errors"
 	"net/mail"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/google/uuid"
 )
@@ -13,6 +15,9 @@ type RegisterRequest struct {
 	Email    Email    `json:"email" example:"mike@mock.com"`
 	Password Password `json:"password" example:"P@ssw0rd"`
 	Company  Company  `json:"company" example:"ey"`
+	// Invitation is required to join an existing organization, Company is
+	// ignored when it is given.
+	Invitation string `json:"invitation"`
 }
 
 type Request struct {
@@ -32,7 +37,7 @@ func (e *Email) UnmarshalJSON(data []byte) error {
 		return err
 	}
 
-	*e = Email(email)
+	*e = Email(strings.ToLower(email))
 	return nil
 }
 
@@ -68,41 +73,194 @@ func (c *Company) UnmarshalJSON(data []byte) error {
 		return errors.New("company should be between 2 and 32 characters")
 	}
 
+	if strings.IndexFunc(company, unicode.IsControl) >= 0 {
+		return errors.New("company should not contain control characters")
+	}
+
 	*c = Company(company)
 	return nil
 }
 
+type PasswordRequest struct {
+	Old Password `json:"old" example:"P@ssw0rd"`
+	New Password `json:"new" example:"N3wP@ssw0rd"`
+}
+
+type EmailRequest struct {
+	Email    Email    `json:"email" example:"mike@example.com"`
+	Password Password `json:"password" example:"P@ssw0rd"`
+}
+
+type CodeRequest struct {
+	Code string `json:"code" example:"123456"`
+}
+
+type LoginVerifyRequest struct {
+	Token string `json:"token"`
+	Code  string `json:"code" example:"123456"`
+}
+
+type DisableTOTPRequest struct {
+	Password Password `json:"password" example:"P@ssw0rd"`
+	Code     string   `json:"code" example:"123456"`
+}
+
+type ForgotRequest struct {
+	Email Email `json:"email" example:"mike@mock.com"`
+}
+
+type ResetRequest struct {
+	Token    string   `json:"token"`
+	Password Password `json:"password" example:"N3wP@ssw0rd"`
+}
+
+type InviteRequest struct {
+	Email Email `json:"email" example:"anna@mock.com"`
+	Role  Role  `json:"role" example:"recruiter"`
+}
+
+type APIKeyRequest struct {
+	Name    string    `json:"name" example:"ATS"`
+	Scopes  []Scope   `json:"scopes" example:"campaigns:read,applications:read"`
+	Expires time.Time `json:"expires"`
+}
+
+type RoleRequest struct {
+	Key  uuid.UUID `json:"key"`
+	Role Role      `json:"role" example:"admin"`
+}
+
+// Valid reports whether the role is one a request may grant. Stored accounts
+// are decoded without this check, since those registered before roles were
+// introduced have none.
+func (r Role) Valid() bool {
+	switch r {
+	case RoleOwner, RoleAdmin, RoleRecruiter, RoleViewer:
+		return true
+	}
+
+	return false
+}
+
+func (s *Scope) UnmarshalJSON(data []byte) error {
+	var scope string
+	if err := json.Unmarshal(data, &scope); err != nil {
+		return err
+	}
+
+	switch Scope(scope) {
+	case ScopeCampaignsRead, ScopeCampaignsWrite, ScopeApplicationsRead, ScopeApplicationsWrite:
+	default:
+		return errors.New("scope should be one of campaigns:read, campaigns:write, applications:read, applications:write")
+	}
+
+	*s = Scope(scope)
+	return nil
+}
+
 // Refactor later
 
 type CreateCampaignRequest struct {
-	Name         string    `json:"name"`
-	Start        time.Time `json:"start"`
-	Finish       time.Time `json:"finish"`
-	Active       bool      `json:"active"`
-	Wanted       int       `json:"wanted"`
-	Accept       float32   `json:"accept"`
-	Reject       float32   `json:"reject"`
-	Education    []string  `json:"education"`
-	Experience   []string  `json:"experience"`
-	Certificates []string  `json:"certificates"`
-	Courses      []string  `json:"courses"`
-	Skills       []string  `json:"skills"`
-	Languages    []string  `json:"languages"`
+	Name         string      `json:"name"`
+	Start        time.Time   `json:"start"`
+	Finish       time.Time   `json:"finish"`
+	Active       bool        `json:"active"`
+	Wanted       int         `json:"wanted"`
+	Accept       float32     `json:"accept"`
+	Reject       float32     `json:"reject"`
+	Weights      *Weights    `json:"weights"`
+	Education    []Criterion `json:"education"`
+	Experience   []Criterion `json:"experience"`
+	Certificates []Criterion `json:"certificates"`
+	Courses      []Criterion `json:"courses"`
+	Skills       []Criterion `json:"skills"`
+	Languages    []Criterion `json:"languages"`
 }
 
 type UpdateCampaignRequest struct {
-	Key          uuid.UUID  `json:"key"`
-	Name         *string    `json:"name"`
-	Start        *time.Time `json:"start"`
-	Finish       *time.Time `json:"finish"`
-	Active       *bool      `json:"active"`
-	Wanted       *int       `json:"wanted"`
-	Accept       *float32   `json:"accept"`
-	Reject       *float32   `json:"reject"`
-	Education    *[]string  `json:"education"`
-	Experience   *[]string  `json:"experience"`
-	Certificates *[]string  `json:"certificates"`
-	Courses      *[]string  `json:"courses"`
-	Skills       *[]string  `json:"skills"`
-	Languages    *[]string  `json:"languages"`
+	Key          uuid.UUID    `json:"key"`
+	Name         *string      `json:"name"`
+	Start        *time.Time   `json:"start"`
+	Finish       *time.Time   `json:"finish"`
+	Active       *bool        `json:"active"`
+	Wanted       *int         `json:"wanted"`
+	Accept       *float32     `json:"accept"`
+	Reject       *float32     `json:"reject"`
+	Weights      *Weights     `json:"weights"`
+	Education    *[]Criterion `json:"education"`
+	Experience   *[]Criterion `json:"experience"`
+	Certificates *[]Criterion `json:"certificates"`
+	Courses      *[]Criterion `json:"courses"`
+	Skills       *[]Criterion `json:"skills"`
+	Languages    *[]Criterion `json:"languages"`
+}
+
+func (c *Criterion) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*c = Criterion{Name: name, Weight: 1}
+		return nil
+	}
+
+	type criterion Criterion
+	value := criterion{Weight: 1}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(value.Name) == "" {
+		return errors.New("criterion name is required")
+	}
+
+	if value.Weight <= 0 {
+		return errors.New("criterion weight should be positive")
+	}
+
+	*c = Criterion(value)
+	return nil
+}
+
+func (w *Weights) UnmarshalJSON(data []byte) error {
+	type weights Weights
+	value := weights(DefaultWeights)
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	for _, weight := range []float32{value.Education, value.Experience, value.Certificates, value.Courses, value.Skills, value.Languages} {
+		if weight < 0 {
+			return errors.New("weights should not be negative")
+		}
+	}
+
+	*w = Weights(value)
+	return nil
+}
+
+type ApplyRequest struct {
+	Name         string   `json:"name" example:"John Doe"`
+	Email        Email    `json:"email" example:"john@mock.com"`
+	Education    []string `json:"education"`
+	Experience   []string `json:"experience"`
+	Certificates []string `json:"certificates"`
+	Courses      []string `json:"courses"`
+	Skills       []string `json:"skills"`
+	Languages    []string `json:"languages"`
+}
+
+type ApplicationStatusRequest struct {
+	Key    uuid.UUID `json:"key"`
+	Status Status    `json:"status" example:"accepted"`
+}
+
+// Valid reports whether the status is one a request may set. Stored
+// applications are decoded without this check, so a document written with an
+// unexpected status can still be read.
+func (s Status) Valid() bool {
+	switch s {
+	case StatusSubmitted, StatusReview, StatusAccepted, StatusRejected:
+		return true
+	}
+
+	return false
 }
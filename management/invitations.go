@@ -0,0 +1,149 @@
+package management
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// @Summary CreateInvitation
+// @Schemes
+// @Description Invite an email address to join the organization
+// @Tags invitations
+// @Accept application/json
+// @Param payload body InviteRequest true "body"
+// @Success 200 {object} InvitationResponse
+// @Failure 400
+// @Failure 401
+// @Router /invitation/create [post]
+func (s *server) CreateInvitation(c *fiber.Ctx) error {
+	var request InviteRequest
+	if err := json.Unmarshal(c.Body(), &request); err != nil {
+		return fiber.NewError(http.StatusBadRequest, err.Error())
+	}
+
+	if request.Email == "" || request.Role == "" {
+		return fiber.NewError(http.StatusBadRequest, "email and role are required")
+	}
+
+	if !request.Role.Valid() {
+		return errRole
+	}
+
+	if request.Role == RoleOwner {
+		return fiber.NewError(http.StatusBadRequest, "owners cannot be invited")
+	}
+
+	_, err := s.organizations.FindByEmail(c.UserContext(), request.Email.String())
+	if err == nil {
+		return fiber.NewError(http.StatusBadRequest, "user with this email already exists")
+	}
+
+	if !errors.Is(err, ErrNotFound) {
+		return storageError(err)
+	}
+
+	token, digest, err := secret()
+	if err != nil {
+		return err
+	}
+
+	invitation := Invitation{
+		Key:          uuid.New(),
+		Organization: principal(c).Organization.Key,
+		Email:        request.Email.String(),
+		Role:         request.Role,
+		Secret:       digest,
+		Expires:      time.Now().Add(time.Duration(s.configuration.Invitation) * time.Hour),
+		Created:      time.Now(),
+	}
+
+	if _, err = s.invitations.Save(c.UserContext(), invitation); err != nil {
+		return storageError(err)
+	}
+
+	token = invitation.Key.String() + "." + token
+	err = s.mailer.Send(c.UserContext(), Message{
+		To:      invitation.Email,
+		Subject: "Invitation to " + principal(c).Organization.Name,
+		Body: fmt.Sprintf("You were invited to join %s, use the link below to register.\n\n%s/register?invitation=%s",
+			principal(c).Organization.Name, s.configuration.URL, url.QueryEscape(token)),
+	})
+	if err != nil {
+		return fiber.NewError(http.StatusServiceUnavailable, err.Error())
+	}
+
+	return c.JSON(InvitationResponse{
+		Key:     invitation.Key,
+		Token:   token,
+		Expires: invitation.Expires,
+	})
+}
+
+// @Summary ListInvitations
+// @Schemes
+// @Description List invitations of the organization
+// @Tags invitations
+// @Accept application/json
+// @Success 200 {array} Invitation
+// @Failure 401
+// @Router /invitations [get]
+func (s *server) ListInvitations(c *fiber.Ctx) error {
+	invitations, err := s.invitations.ListByOrganization(c.UserContext(), principal(c).Organization.Key)
+	if err != nil {
+		return storageError(err)
+	}
+
+	for i := range invitations {
+		invitations[i].Secret = nil
+	}
+
+	return c.JSON(invitations)
+}
+
+// redeem validates an invitation token presented by email and marks the
+// invitation used, so it cannot be redeemed again.
+func (s *server) redeem(c *fiber.Ctx, token, email string) (Invitation, error) {
+	invalid := fiber.NewError(http.StatusBadRequest, "invalid invitation")
+
+	key, value, ok := split(token)
+	if !ok {
+		return Invitation{}, invalid
+	}
+
+	invitation, err := s.invitations.FindByKey(c.UserContext(), key)
+	if errors.Is(err, ErrNotFound) {
+		return Invitation{}, invalid
+	}
+
+	if err != nil {
+		return Invitation{}, storageError(err)
+	}
+
+	if subtle.ConstantTimeCompare(invitation.Secret, hash(value)) != 1 || !strings.EqualFold(invitation.Email, email) {
+		return Invitation{}, invalid
+	}
+
+	if invitation.Used || time.Now().After(invitation.Expires) {
+		return Invitation{}, fiber.NewError(http.StatusBadRequest, "invitation expired")
+	}
+
+	invitation.Used = true
+	if _, err = s.invitations.Save(c.UserContext(), invitation); errors.Is(err, ErrConflict) {
+		return Invitation{}, fiber.NewError(http.StatusBadRequest, "invitation expired")
+	}
+
+	if err != nil {
+		return Invitation{}, storageError(err)
+	}
+
+	return invitation, nil
+}
@@ -0,0 +1,52 @@
+package management
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestInvitation(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.register(t, "owner@example.com", "Acme")
+
+	owner.expect(http.StatusBadRequest, "POST", "/invitation/create", `{"email":"anna@example.com","role":"owner"}`)
+	owner.expect(http.StatusBadRequest, "POST", "/invitation/create", `{"email":"anna@example.com","role":"root"}`)
+	owner.expect(http.StatusBadRequest, "POST", "/invitation/create", `{"email":"owner@example.com","role":"viewer"}`)
+
+	var invitation InvitationResponse
+	decode(t, owner.expect(http.StatusOK, "POST", "/invitation/create", `{"email":"anna@example.com","role":"recruiter"}`), &invitation)
+	s.link(t, s.mailer.last(t, "anna@example.com"), "/register?invitation=")
+
+	register := func(email string) (int, string) {
+		return s.client(t).do("POST", "/account/register", `{"email":"`+email+`","password":"secret1","invitation":"`+invitation.Token+`"}`)
+	}
+
+	if code, data := register("other@example.com"); code != http.StatusBadRequest {
+		t.Fatalf("invitation redeemed by another address: %d %s", code, data)
+	}
+
+	client := s.client(t)
+	client.expect(http.StatusOK, "POST", "/account/register", `{"email":"anna@example.com","password":"secret1","invitation":"`+invitation.Token+`"}`)
+	if code, data := register("anna@example.com"); code != http.StatusBadRequest {
+		t.Fatalf("invitation redeemed twice: %d %s", code, data)
+	}
+
+	// the inviting admin saw the token too, so the address is verified by mail
+	var profile Member
+	decode(t, client.expect(http.StatusOK, "GET", "/account/profile", ""), &profile)
+	if profile.Role != RoleRecruiter || profile.Verified {
+		t.Fatalf("unexpected profile %+v", profile)
+	}
+
+	s.verify(t, "anna@example.com")
+	decode(t, client.expect(http.StatusOK, "GET", "/account/profile", ""), &profile)
+	if !profile.Verified {
+		t.Fatal("account not verified")
+	}
+
+	var identity Identity
+	decode(t, client.expect(http.StatusOK, "GET", "/account/authorize", ""), &identity)
+	if identity.Company != "Acme" {
+		t.Fatalf("registered into %s", identity.Company)
+	}
+}
@@ -0,0 +1,157 @@
+package management
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// Keyring signs session tokens with its current key and verifies them with any
+// key it holds, picked by the kid header, so keys can be rotated without
+// signing everyone out.
+type Keyring struct {
+	current signingKey
+	keys    map[string]signingKey
+	// legacy verifies tokens issued before they carried a kid.
+	legacy string
+}
+
+type signingKey struct {
+	id     string
+	method jwt.SigningMethod
+	sign   interface{}
+	verify interface{}
+}
+
+// NewKeyring holds Config.Secret and the previous Config.Secrets as HS256
+// keys. When Config.SigningKey names a PEM encoded RSA or Ed25519 private key
+// it signs with RS256 or EdDSA instead, while the public keys listed in
+// Config.VerificationKeys are still accepted.
+func NewKeyring(c Config) (*Keyring, error) {
+	keyring := &Keyring{keys: map[string]signingKey{}}
+	for i, secret := range append([]string{c.Secret}, c.Secrets...) {
+		if secret == "" {
+			continue
+		}
+
+		key := signingKey{id: keyID([]byte(secret)), method: jwt.SigningMethodHS256, sign: []byte(secret), verify: []byte(secret)}
+		keyring.keys[key.id] = key
+		if i == 0 {
+			keyring.current, keyring.legacy = key, key.id
+		}
+	}
+
+	if c.SigningKey != "" {
+		key, err := loadPrivateKey(c.SigningKey)
+		if err != nil {
+			return nil, err
+		}
+
+		keyring.keys[key.id] = key
+		keyring.current = key
+	}
+
+	for _, path := range c.VerificationKeys {
+		key, err := loadPublicKey(path)
+		if err != nil {
+			return nil, err
+		}
+
+		keyring.keys[key.id] = key
+	}
+
+	if keyring.current.sign == nil {
+		return nil, errors.New("no signing key configured")
+	}
+
+	return keyring, nil
+}
+
+func (k *Keyring) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.current.method, claims)
+	token.Header["kid"] = k.current.id
+	return token.SignedString(k.current.sign)
+}
+
+// key returns the key verifying a token, refusing any other algorithm than
+// the one the key signs with.
+func (k *Keyring) key(token *jwt.Token) (interface{}, error) {
+	id, _ := token.Header["kid"].(string)
+	if id == "" {
+		id = k.legacy
+	}
+
+	key, ok := k.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", id)
+	}
+
+	if token.Method != key.method {
+		return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+	}
+
+	return key.verify, nil
+}
+
+func loadPrivateKey(path string) (signingKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return signingKey{}, err
+	}
+
+	if private, err := jwt.ParseRSAPrivateKeyFromPEM(data); err == nil {
+		return publicKey(jwt.SigningMethodRS256, private, private.Public())
+	}
+
+	if private, err := jwt.ParseEdPrivateKeyFromPEM(data); err == nil {
+		return publicKey(jwt.SigningMethodEdDSA, private, private.(crypto.Signer).Public())
+	}
+
+	return signingKey{}, fmt.Errorf("%s is neither an RSA nor an Ed25519 private key", path)
+}
+
+func loadPublicKey(path string) (signingKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return signingKey{}, err
+	}
+
+	if public, err := jwt.ParseRSAPublicKeyFromPEM(data); err == nil {
+		return publicKey(jwt.SigningMethodRS256, nil, public)
+	}
+
+	if public, err := jwt.ParseEdPublicKeyFromPEM(data); err == nil {
+		return publicKey(jwt.SigningMethodEdDSA, nil, public)
+	}
+
+	return signingKey{}, fmt.Errorf("%s is neither an RSA nor an Ed25519 public key", path)
+}
+
+func publicKey(method jwt.SigningMethod, private, public interface{}) (signingKey, error) {
+	switch public.(type) {
+	case *rsa.PublicKey, ed25519.PublicKey:
+	default:
+		return signingKey{}, errors.New("unsupported public key")
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		return signingKey{}, err
+	}
+
+	return signingKey{id: keyID(der), method: method, sign: private, verify: public}, nil
+}
+
+// keyID derives a stable kid from the key material without revealing it.
+func keyID(material []byte) string {
+	sum := sha256.Sum256(material)
+	return base64.RawURLEncoding.EncodeToString(sum[:9])
+}
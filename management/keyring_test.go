@@ -0,0 +1,170 @@
+package management
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// writeKeys writes the PEM encoded private and public key to the
+// files name.pem and name.pub.
+func writeKeys(t *testing.T, name string, private interface{}, public interface{}) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	privatePath := filepath.Join(dir, name+".pem")
+	if err := os.WriteFile(privatePath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if der, err = x509.MarshalPKIXPublicKey(public); err != nil {
+		t.Fatal(err)
+	}
+
+	publicPath := filepath.Join(dir, name+".pub")
+	if err := os.WriteFile(publicPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return privatePath, publicPath
+}
+
+func testKeys(t *testing.T) (rsaPrivate, rsaPublic, edPrivate, edPublic string) {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	edPublicKey, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rsaPrivate, rsaPublic = writeKeys(t, "rsa", rsaKey, &rsaKey.PublicKey)
+	edPrivate, edPublic = writeKeys(t, "ed25519", edKey, edPublicKey)
+	return
+}
+
+func testKeyring(t *testing.T, c Config) *Keyring {
+	t.Helper()
+	keyring, err := NewKeyring(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return keyring
+}
+
+func testClaims() jwt.StandardClaims {
+	return jwt.StandardClaims{Subject: "owner@example.com", ExpiresAt: time.Now().Add(time.Hour).Unix()}
+}
+
+func TestKeyring(t *testing.T) {
+	rsaPrivate, rsaPublic, edPrivate, edPublic := testKeys(t)
+
+	for _, test := range []struct {
+		name     string
+		signer   Config
+		verifier Config
+		// legacy signs without a kid, as tokens were before keyrings.
+		legacy bool
+		valid  bool
+	}{
+		{"same secret", Config{Secret: "old"}, Config{Secret: "old"}, false, true},
+		{"rotated secret", Config{Secret: "old"}, Config{Secret: "new", Secrets: []string{"old"}}, false, true},
+		{"retired secret", Config{Secret: "old"}, Config{Secret: "new"}, false, false},
+		{"legacy token", Config{Secret: "old"}, Config{Secret: "old", Secrets: []string{"older"}}, true, true},
+		{"legacy token of another secret", Config{Secret: "old"}, Config{Secret: "new", Secrets: []string{"older"}}, true, false},
+		{"rs256", Config{SigningKey: rsaPrivate}, Config{SigningKey: rsaPrivate}, false, true},
+		{"rs256 public key", Config{SigningKey: rsaPrivate}, Config{Secret: "new", VerificationKeys: []string{rsaPublic}}, false, true},
+		{"eddsa", Config{SigningKey: edPrivate}, Config{SigningKey: edPrivate}, false, true},
+		{"rs256 rotated to eddsa", Config{SigningKey: rsaPrivate}, Config{SigningKey: edPrivate, VerificationKeys: []string{rsaPublic}}, false, true},
+		{"secret rotated to eddsa", Config{Secret: "old"}, Config{Secret: "old", SigningKey: edPrivate}, false, true},
+		{"retired rs256", Config{SigningKey: rsaPrivate}, Config{SigningKey: edPrivate, VerificationKeys: []string{edPublic}}, false, false},
+		{"secret for rs256", Config{Secret: "old"}, Config{SigningKey: rsaPrivate}, false, false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			signer := testKeyring(t, test.signer)
+			token, err := signer.sign(testClaims())
+			if test.legacy {
+				token, err = jwt.NewWithClaims(signer.current.method, testClaims()).SignedString(signer.current.sign)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = jwt.Parse(token, testKeyring(t, test.verifier).key)
+			if test.valid && err != nil {
+				t.Fatalf("refused: %v", err)
+			}
+			if !test.valid && err == nil {
+				t.Fatal("accepted")
+			}
+		})
+	}
+}
+
+func TestKeyringAlgorithm(t *testing.T) {
+	rsaPrivate, rsaPublic, _, _ := testKeys(t)
+	keyring := testKeyring(t, Config{Secret: "secret", SigningKey: rsaPrivate, VerificationKeys: []string{rsaPublic}})
+	public, err := os.ReadFile(rsaPublic)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sign := func(method jwt.SigningMethod, kid string, key interface{}) string {
+		token := jwt.NewWithClaims(method, testClaims())
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return signed
+	}
+
+	for name, token := range map[string]string{
+		// the public key is no secret, an HMAC of it must not pass for RS256
+		"hs256 with the rsa kid":                     sign(jwt.SigningMethodHS256, keyring.current.id, public),
+		"hs256 with the public key as legacy secret": sign(jwt.SigningMethodHS256, "", public),
+		"rs256 with the secret kid":                  sign(jwt.SigningMethodRS256, keyID([]byte("secret")), keyring.current.sign),
+		"none":                                       sign(jwt.SigningMethodNone, keyring.current.id, jwt.UnsafeAllowNoneSignatureType),
+		"unknown kid":                                sign(jwt.SigningMethodHS256, "unknown", []byte("secret")),
+	} {
+		if _, err := jwt.Parse(token, keyring.key); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+}
+
+func TestNewKeyring(t *testing.T) {
+	_, rsaPublic, _, _ := testKeys(t)
+
+	for name, c := range map[string]Config{
+		"without keys":             {},
+		"public key for signing":   {SigningKey: rsaPublic},
+		"missing signing key":      {SigningKey: filepath.Join(t.TempDir(), "missing.pem")},
+		"missing verification key": {Secret: "secret", VerificationKeys: []string{filepath.Join(t.TempDir(), "missing.pub")}},
+	} {
+		if _, err := NewKeyring(c); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package management
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// smtpTimeout bounds the delivery of a message when the context has no earlier
+// deadline.
+const smtpTimeout = 30 * time.Second
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// NewMailer returns an SMTP mailer when Config.SMTPHost is set. Otherwise
+// messages are written to Config.MailFile, or in development mode to the
+// standard logger when no file is configured. Messages carry tokens, so they
+// are never logged outside development mode.
+func NewMailer(c Config) (Mailer, error) {
+	if c.SMTPHost != "" {
+		return NewSMTPMailer(c.SMTPHost, c.SMTPPort, c.SMTPUsername, c.SMTPPassword, c.MailFrom), nil
+	}
+
+	if c.MailFile == "" {
+		if c.Mode != ModeDevelopment {
+			return nil, errors.New("SMTP_HOST or MAIL_FILE has to be set outside of development mode")
+		}
+
+		return NewLogMailer(log.Writer()), nil
+	}
+
+	file, err := os.OpenFile(c.MailFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewLogMailer(file), nil
+}
+
+type smtpMailer struct {
+	host    string
+	address string
+	from    string
+	auth    smtp.Auth
+}
+
+func NewSMTPMailer(host string, port int, username, password, from string) Mailer {
+	mailer := &smtpMailer{host: host, address: net.JoinHostPort(host, strconv.Itoa(port)), from: from}
+	if username != "" {
+		mailer.auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return mailer
+}
+
+// Send encodes the header values, so a subject or recipient cannot add
+// headers of its own.
+func (m *smtpMailer) Send(ctx context.Context, message Message) error {
+	to, err := mail.ParseAddress(message.To)
+	if err != nil {
+		return err
+	}
+
+	var data strings.Builder
+	fmt.Fprintf(&data, "From: %s\r\n", m.from)
+	fmt.Fprintf(&data, "To: %s\r\n", to.String())
+	fmt.Fprintf(&data, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", message.Subject))
+	data.WriteString("MIME-Version: 1.0\r\n")
+	data.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	data.WriteString(strings.ReplaceAll(message.Body, "\n", "\r\n"))
+
+	return m.send(ctx, to.Address, []byte(data.String()))
+}
+
+// send delivers the message like smtp.SendMail, giving up at the deadline of
+// ctx or after smtpTimeout.
+func (m *smtpMailer) send(ctx context.Context, to string, data []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, smtpTimeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", m.address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	deadline, _ := ctx.Deadline()
+	if err = conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err = client.StartTLS(&tls.Config{ServerName: m.host}); err != nil {
+			return err
+		}
+	}
+
+	if m.auth != nil {
+		if err = client.Auth(m.auth); err != nil {
+			return err
+		}
+	}
+
+	if err = client.Mail(m.from); err != nil {
+		return err
+	}
+
+	if err = client.Rcpt(to); err != nil {
+		return err
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+
+	if _, err = writer.Write(data); err != nil {
+		return err
+	}
+
+	if err = writer.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+type logMailer struct {
+	mutex  sync.Mutex
+	writer io.Writer
+}
+
+func NewLogMailer(w io.Writer) Mailer {
+	return &logMailer{writer: w}
+}
+
+func (m *logMailer) Send(ctx context.Context, message Message) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	_, err := fmt.Fprintf(m.writer, "To: %s\nSubject: %s\n\n%s\n\n", message.To, message.Subject, message.Body)
+	return err
+}
@@ -0,0 +1,185 @@
+package management
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// smtpServer accepts a single message and returns its data.
+func smtpServer(t *testing.T) (string, int, <-chan string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	messages := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+		reply("220 localhost")
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+
+			switch command := strings.ToUpper(strings.TrimSpace(line)); {
+			case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+				reply("250 localhost")
+			case command == "DATA":
+				reply("354 go ahead")
+				var data strings.Builder
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil || line == ".\r\n" {
+						break
+					}
+					data.WriteString(line)
+				}
+				messages <- data.String()
+				reply("250 queued")
+			case command == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	number, _ := strconv.Atoi(port)
+	return host, number, messages
+}
+
+func TestSMTPMailerHeaders(t *testing.T) {
+	host, port, messages := smtpServer(t)
+	mailer := NewSMTPMailer(host, port, "", "", "noreply@example.com")
+
+	err := mailer.Send(context.Background(), Message{
+		To:      "anna@example.com",
+		Subject: "Invitation to x\r\nBcc: evil@example.com",
+		Body:    "line\nBcc: body@example.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := <-messages
+	headers, body, _ := strings.Cut(data, "\r\n\r\n")
+	for _, line := range strings.Split(headers, "\r\n") {
+		if strings.HasPrefix(strings.ToLower(line), "bcc:") {
+			t.Fatalf("header injected in %q", headers)
+		}
+	}
+
+	if !strings.Contains(headers, "To: <anna@example.com>") || !strings.Contains(headers, "Subject: =?utf-8?q?") {
+		t.Fatalf("unexpected headers %q", headers)
+	}
+
+	if body != "line\r\nBcc: body@example.com\r\n" {
+		t.Fatalf("unexpected body %q", body)
+	}
+}
+
+func TestSMTPMailerRecipient(t *testing.T) {
+	mailer := NewSMTPMailer("127.0.0.1", 1, "", "", "noreply@example.com")
+	err := mailer.Send(context.Background(), Message{To: "anna@example.com\r\nBcc: evil@example.com", Subject: "Hi"})
+	if err == nil {
+		t.Fatal("recipient with a header accepted")
+	}
+}
+
+func TestSMTPMailerDeadline(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	// accept and never answer
+	go func() {
+		if conn, err := listener.Accept(); err == nil {
+			defer conn.Close()
+			time.Sleep(5 * time.Second)
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	number, _ := strconv.Atoi(port)
+	mailer := NewSMTPMailer(host, number, "", "", "noreply@example.com")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if err = mailer.Send(ctx, Message{To: "anna@example.com", Subject: "Hi"}); err == nil {
+		t.Fatal("sent to a server that never answered")
+	}
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("gave up after %s", elapsed)
+	}
+}
+
+func TestNewMailer(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "mail.log")
+	tests := []struct {
+		name   string
+		config Config
+		kind   Mailer
+	}{
+		{"smtp", Config{Mode: "production", SMTPHost: "smtp.example.com"}, &smtpMailer{}},
+		{"file", Config{Mode: "production", MailFile: file}, &logMailer{}},
+		{"development log", Config{Mode: ModeDevelopment}, &logMailer{}},
+		{"production log", Config{Mode: "production"}, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mailer, err := NewMailer(test.config)
+			if test.kind == nil {
+				if err == nil {
+					t.Fatalf("got %T, want an error", mailer)
+				}
+				return
+			}
+
+			if err != nil || reflect.TypeOf(mailer) != reflect.TypeOf(test.kind) {
+				t.Fatalf("got %T %v, want %T", mailer, err, test.kind)
+			}
+		})
+	}
+}
+
+func TestCompanyUnmarshal(t *testing.T) {
+	for data, valid := range map[string]bool{
+		`"Acme"`:                            true,
+		`"Acme Polska"`:                     true,
+		`"A"`:                               false,
+		`"x\r\nBcc: evil@example.com"`:      false,
+		`"tab\tname"`:                       false,
+		`"` + strings.Repeat("a", 33) + `"`: false,
+	} {
+		var company Company
+		if err := json.Unmarshal([]byte(data), &company); (err == nil) != valid {
+			t.Errorf("%s: got %v, want valid %v", data, err, valid)
+		}
+	}
+}
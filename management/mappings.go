@@ -0,0 +1,205 @@
+package management
+
+// Keys, emails and names are keywords so lookups match them exactly, emails
+// and organization names with the built-in lowercase normalizer. Secrets and
+// password hashes are kept in _source only.
+//
+// Each mapping is suffixed with the migration that introduced it. Applied
+// migrations keep using their snapshot, so a snapshot must never change: a
+// new schema is a new snapshot, applied by a new migration.
+const (
+	organizationsMapping1 = `{
+		"properties": {
+			"key": { "type": "keyword" },
+			"name": { "type": "keyword", "normalizer": "lowercase" },
+			"accounts": {
+				"type": "nested",
+				"properties": {
+					"key": { "type": "keyword" },
+					"email": { "type": "keyword", "normalizer": "lowercase" },
+					"password": { "type": "keyword", "index": false, "doc_values": false },
+					"role": { "type": "keyword" },
+					"verified": { "type": "boolean" },
+					"failures": { "type": "integer" },
+					"locked": { "type": "date" },
+					"totp": { "type": "object", "enabled": false },
+					"subject": { "type": "keyword" },
+					"created": { "type": "date" }
+				}
+			},
+			"campaigns": {
+				"type": "nested",
+				"dynamic": false,
+				"properties": {
+					"key": { "type": "keyword" }
+				}
+			},
+			"keys": {
+				"properties": {
+					"key": { "type": "keyword" },
+					"name": { "type": "keyword" },
+					"secret": { "type": "keyword", "index": false, "doc_values": false },
+					"scopes": { "type": "keyword" },
+					"expires": { "type": "date" },
+					"created": { "type": "date" }
+				}
+			},
+			"created": { "type": "date" }
+		}
+	}`
+
+	campaignsMapping1 = `{
+		"properties": {
+			"key": { "type": "keyword" },
+			"organization": { "type": "keyword" },
+			"name": { "type": "keyword" },
+			"start": { "type": "date" },
+			"finish": { "type": "date" },
+			"active": { "type": "boolean" },
+			"wanted": { "type": "integer" },
+			"accept": { "type": "float" },
+			"reject": { "type": "float" },
+			"weights": { "type": "object", "enabled": false },
+			"education": { "type": "object", "enabled": false },
+			"experience": { "type": "object", "enabled": false },
+			"certificates": { "type": "object", "enabled": false },
+			"courses": { "type": "object", "enabled": false },
+			"skills": { "type": "object", "enabled": false },
+			"languages": { "type": "object", "enabled": false },
+			"created": { "type": "date" },
+			"updated": { "type": "date" }
+		}
+	}`
+
+	applicationsMapping1 = `{
+		"properties": {
+			"key": { "type": "keyword" },
+			"organization": { "type": "keyword" },
+			"campaign": { "type": "keyword" },
+			"name": { "type": "text" },
+			"email": { "type": "keyword", "normalizer": "lowercase" },
+			"education": { "type": "keyword" },
+			"experience": { "type": "keyword" },
+			"certificates": { "type": "keyword" },
+			"courses": { "type": "keyword" },
+			"skills": { "type": "keyword" },
+			"languages": { "type": "keyword" },
+			"score": { "type": "object", "enabled": false },
+			"status": { "type": "keyword" },
+			"created": { "type": "date" },
+			"updated": { "type": "date" }
+		}
+	}`
+
+	sessionsMapping1 = `{
+		"properties": {
+			"key": { "type": "keyword" },
+			"family": { "type": "keyword" },
+			"organization": { "type": "keyword" },
+			"account": { "type": "keyword" },
+			"token": { "type": "keyword" },
+			"secret": { "type": "keyword", "index": false, "doc_values": false },
+			"rotated": { "type": "boolean" },
+			"revoked": { "type": "boolean" },
+			"expires": { "type": "date" },
+			"created": { "type": "date" }
+		}
+	}`
+
+	revocationsMapping1 = `{
+		"properties": {
+			"token": { "type": "keyword" },
+			"expires": { "type": "date" }
+		}
+	}`
+
+	invitationsMapping1 = `{
+		"properties": {
+			"key": { "type": "keyword" },
+			"organization": { "type": "keyword" },
+			"email": { "type": "keyword", "normalizer": "lowercase" },
+			"role": { "type": "keyword" },
+			"secret": { "type": "keyword", "index": false, "doc_values": false },
+			"used": { "type": "boolean" },
+			"expires": { "type": "date" },
+			"created": { "type": "date" }
+		}
+	}`
+
+	ticketsMapping1 = `{
+		"properties": {
+			"key": { "type": "keyword" },
+			"purpose": { "type": "keyword" },
+			"organization": { "type": "keyword" },
+			"account": { "type": "keyword" },
+			"secret": { "type": "keyword", "index": false, "doc_values": false },
+			"used": { "type": "boolean" },
+			"expires": { "type": "date" },
+			"created": { "type": "date" }
+		}
+	}`
+
+	ticketsMapping4 = `{
+		"properties": {
+			"key": { "type": "keyword" },
+			"purpose": { "type": "keyword" },
+			"organization": { "type": "keyword" },
+			"account": { "type": "keyword" },
+			"email": { "type": "keyword", "normalizer": "lowercase" },
+			"secret": { "type": "keyword", "index": false, "doc_values": false },
+			"used": { "type": "boolean" },
+			"expires": { "type": "date" },
+			"created": { "type": "date" }
+		}
+	}`
+
+	// migrationsMapping is created before any migration runs and cannot
+	// change either.
+	migrationsMapping = `{
+		"dynamic": false,
+		"properties": {
+			"version": { "type": "integer" },
+			"name": { "type": "keyword" },
+			"applied": { "type": "date" },
+			"owner": { "type": "keyword" },
+			"expires": { "type": "date" }
+		}
+	}`
+)
+
+// The current mappings, which instances verify at startup.
+const (
+	organizationsMapping = organizationsMapping1
+	campaignsMapping     = campaignsMapping1
+	applicationsMapping  = applicationsMapping1
+	sessionsMapping      = sessionsMapping1
+	revocationsMapping   = revocationsMapping1
+	invitationsMapping   = invitationsMapping1
+	ticketsMapping       = ticketsMapping4
+)
+
+// mappings1 are the mappings migration 1 created templates with.
+func mappings1(c Config) map[string]string {
+	return map[string]string{
+		c.Index:        organizationsMapping1,
+		c.Campaigns:    campaignsMapping1,
+		c.Applications: applicationsMapping1,
+		c.Sessions:     sessionsMapping1,
+		c.Revocations:  revocationsMapping1,
+		c.Invitations:  invitationsMapping1,
+		c.Tickets:      ticketsMapping1,
+	}
+}
+
+// mappings are the current mappings of the indices behind each alias.
+func mappings(c Config) map[string]string {
+	return map[string]string{
+		c.Index:        organizationsMapping,
+		c.Campaigns:    campaignsMapping,
+		c.Applications: applicationsMapping,
+		c.Sessions:     sessionsMapping,
+		c.Revocations:  revocationsMapping,
+		c.Invitations:  invitationsMapping,
+		c.Tickets:      ticketsMapping,
+	}
+}
@@ -0,0 +1,456 @@
+package management
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// NewMemoryStorage returns a Storage kept entirely in process memory,
+// intended for tests and local development without a cluster.
+func NewMemoryStorage() Storage {
+	return Storage{
+		Organizations: &memoryOrganizations{documents: newMemoryDocuments()},
+		Campaigns:     &memoryCampaigns{documents: newMemoryDocuments()},
+		Applications:  &memoryApplications{documents: newMemoryDocuments()},
+		Sessions:      &memorySessions{documents: newMemoryDocuments()},
+		Revocations:   &memoryRevocations{documents: newMemoryDocuments()},
+		Invitations:   &memoryInvitations{documents: newMemoryDocuments()},
+		Tickets:       &memoryTickets{documents: newMemoryDocuments()},
+	}
+}
+
+type memoryOrganizations struct {
+	documents *memoryDocuments
+}
+
+func (r *memoryOrganizations) FindByEmail(ctx context.Context, email string) (Organization, error) {
+	return r.find(func(o Organization) bool {
+		for _, account := range o.Accounts {
+			if strings.EqualFold(account.Email, email) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+func (r *memoryOrganizations) FindByName(ctx context.Context, name string) (Organization, error) {
+	return r.find(func(o Organization) bool { return strings.EqualFold(o.Name, name) })
+}
+
+func (r *memoryOrganizations) FindByKey(ctx context.Context, key uuid.UUID) (Organization, error) {
+	var organization Organization
+	version, err := r.documents.get(key, &organization)
+	if err != nil {
+		return Organization{}, err
+	}
+
+	organization.Version = version
+	return organization, nil
+}
+
+func (r *memoryOrganizations) FindByAPIKey(ctx context.Context, key uuid.UUID) (Organization, error) {
+	return r.find(func(o Organization) bool {
+		_, ok := o.APIKey(key)
+		return ok
+	})
+}
+
+func (r *memoryOrganizations) Save(ctx context.Context, organization Organization) (Version, error) {
+	return r.documents.save(organization.Key, organization, organization.Version)
+}
+
+func (r *memoryOrganizations) find(match func(Organization) bool) (Organization, error) {
+	var found *Organization
+	err := r.documents.each(func(document memoryDocument) (bool, error) {
+		var organization Organization
+		if err := document.decode(&organization); err != nil {
+			return false, err
+		}
+
+		if !match(organization) {
+			return true, nil
+		}
+
+		organization.Version = document.version
+		found = &organization
+		return false, nil
+	})
+	if err != nil {
+		return Organization{}, err
+	}
+
+	if found == nil {
+		return Organization{}, ErrNotFound
+	}
+
+	return *found, nil
+}
+
+type memoryCampaigns struct {
+	documents *memoryDocuments
+}
+
+func (r *memoryCampaigns) FindByKey(ctx context.Context, key uuid.UUID) (Campaign, error) {
+	var campaign Campaign
+	version, err := r.documents.get(key, &campaign)
+	if err != nil {
+		return Campaign{}, err
+	}
+
+	campaign.Version = version
+	return campaign, nil
+}
+
+func (r *memoryCampaigns) ListByOrganization(ctx context.Context, organization uuid.UUID) ([]Campaign, error) {
+	campaigns := []Campaign{}
+	err := r.documents.each(func(document memoryDocument) (bool, error) {
+		var campaign Campaign
+		if err := document.decode(&campaign); err != nil {
+			return false, err
+		}
+
+		if campaign.Organization == organization {
+			campaign.Version = document.version
+			campaigns = append(campaigns, campaign)
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(campaigns, func(i, j int) bool { return campaigns[i].Created.Before(campaigns[j].Created) })
+	return campaigns, nil
+}
+
+func (r *memoryCampaigns) Save(ctx context.Context, campaign Campaign) (Version, error) {
+	return r.documents.save(campaign.Key, campaign, campaign.Version)
+}
+
+func (r *memoryCampaigns) Remove(ctx context.Context, campaign Campaign) error {
+	return r.documents.remove(campaign.Key, campaign.Version)
+}
+
+type memoryApplications struct {
+	documents *memoryDocuments
+}
+
+func (r *memoryApplications) FindByKey(ctx context.Context, key uuid.UUID) (Application, error) {
+	var application Application
+	version, err := r.documents.get(key, &application)
+	if err != nil {
+		return Application{}, err
+	}
+
+	application.Version = version
+	return application, nil
+}
+
+func (r *memoryApplications) FindByEmail(ctx context.Context, campaign uuid.UUID, email string) (Application, error) {
+	var found *Application
+	err := r.documents.each(func(document memoryDocument) (bool, error) {
+		var application Application
+		if err := document.decode(&application); err != nil {
+			return false, err
+		}
+
+		if application.Campaign != campaign || !strings.EqualFold(application.Email, email) {
+			return true, nil
+		}
+
+		application.Version = document.version
+		found = &application
+		return false, nil
+	})
+	if err != nil {
+		return Application{}, err
+	}
+
+	if found == nil {
+		return Application{}, ErrNotFound
+	}
+
+	return *found, nil
+}
+
+func (r *memoryApplications) ListByCampaign(ctx context.Context, campaign uuid.UUID) ([]Application, error) {
+	applications := []Application{}
+	err := r.documents.each(func(document memoryDocument) (bool, error) {
+		var application Application
+		if err := document.decode(&application); err != nil {
+			return false, err
+		}
+
+		if application.Campaign == campaign {
+			application.Version = document.version
+			applications = append(applications, application)
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(applications, func(i, j int) bool { return applications[i].Created.Before(applications[j].Created) })
+	return applications, nil
+}
+
+func (r *memoryApplications) Save(ctx context.Context, application Application) (Version, error) {
+	return r.documents.save(application.Key, application, application.Version)
+}
+
+type memorySessions struct {
+	documents *memoryDocuments
+}
+
+func (r *memorySessions) FindByKey(ctx context.Context, key uuid.UUID) (Session, error) {
+	var session Session
+	version, err := r.documents.get(key, &session)
+	if err != nil {
+		return Session{}, err
+	}
+
+	session.Version = version
+	return session, nil
+}
+
+func (r *memorySessions) ListByFamily(ctx context.Context, family uuid.UUID) ([]Session, error) {
+	return r.list(func(session Session) bool { return session.Family == family })
+}
+
+func (r *memorySessions) ListByAccount(ctx context.Context, account uuid.UUID) ([]Session, error) {
+	return r.list(func(session Session) bool { return session.Account == account })
+}
+
+func (r *memorySessions) Save(ctx context.Context, session Session) (Version, error) {
+	return r.documents.save(session.Key, session, session.Version)
+}
+
+func (r *memorySessions) list(match func(Session) bool) ([]Session, error) {
+	sessions := []Session{}
+	err := r.documents.each(func(document memoryDocument) (bool, error) {
+		var session Session
+		if err := document.decode(&session); err != nil {
+			return false, err
+		}
+
+		if match(session) {
+			session.Version = document.version
+			sessions = append(sessions, session)
+		}
+		return true, nil
+	})
+
+	return sessions, err
+}
+
+type memoryRevocations struct {
+	documents *memoryDocuments
+}
+
+func (r *memoryRevocations) Revoke(ctx context.Context, revocation Revocation) error {
+	_, err := r.documents.save(revocation.Token, revocation, Version{})
+	if errors.Is(err, ErrConflict) {
+		return nil
+	}
+
+	return err
+}
+
+func (r *memoryRevocations) IsRevoked(ctx context.Context, token uuid.UUID) (bool, error) {
+	var revocation Revocation
+	if _, err := r.documents.get(token, &revocation); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (r *memoryRevocations) Prune(ctx context.Context, now time.Time) error {
+	return r.documents.removeAll(func(document memoryDocument) (bool, error) {
+		var revocation Revocation
+		if err := document.decode(&revocation); err != nil {
+			return false, err
+		}
+
+		return revocation.Expires.Before(now), nil
+	})
+}
+
+type memoryInvitations struct {
+	documents *memoryDocuments
+}
+
+func (r *memoryInvitations) FindByKey(ctx context.Context, key uuid.UUID) (Invitation, error) {
+	var invitation Invitation
+	version, err := r.documents.get(key, &invitation)
+	if err != nil {
+		return Invitation{}, err
+	}
+
+	invitation.Version = version
+	return invitation, nil
+}
+
+func (r *memoryInvitations) ListByOrganization(ctx context.Context, organization uuid.UUID) ([]Invitation, error) {
+	invitations := []Invitation{}
+	err := r.documents.each(func(document memoryDocument) (bool, error) {
+		var invitation Invitation
+		if err := document.decode(&invitation); err != nil {
+			return false, err
+		}
+
+		if invitation.Organization == organization {
+			invitation.Version = document.version
+			invitations = append(invitations, invitation)
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(invitations, func(i, j int) bool { return invitations[i].Created.Before(invitations[j].Created) })
+	return invitations, nil
+}
+
+func (r *memoryInvitations) Save(ctx context.Context, invitation Invitation) (Version, error) {
+	return r.documents.save(invitation.Key, invitation, invitation.Version)
+}
+
+type memoryTickets struct {
+	documents *memoryDocuments
+}
+
+func (r *memoryTickets) FindByKey(ctx context.Context, key uuid.UUID) (Ticket, error) {
+	var ticket Ticket
+	version, err := r.documents.get(key, &ticket)
+	if err != nil {
+		return Ticket{}, err
+	}
+
+	ticket.Version = version
+	return ticket, nil
+}
+
+func (r *memoryTickets) Save(ctx context.Context, ticket Ticket) (Version, error) {
+	return r.documents.save(ticket.Key, ticket, ticket.Version)
+}
+
+// memoryDocument keeps values serialized so callers never share slices with
+// the store.
+type memoryDocument struct {
+	data    []byte
+	version Version
+}
+
+func (d memoryDocument) decode(target interface{}) error {
+	return json.Unmarshal(d.data, target)
+}
+
+// memoryDocuments mirrors the versioning rules of elasticDocuments.
+type memoryDocuments struct {
+	mutex     sync.RWMutex
+	documents map[uuid.UUID]memoryDocument
+}
+
+func newMemoryDocuments() *memoryDocuments {
+	return &memoryDocuments{documents: map[uuid.UUID]memoryDocument{}}
+}
+
+func (d *memoryDocuments) get(key uuid.UUID, target interface{}) (Version, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	document, ok := d.documents[key]
+	if !ok {
+		return Version{}, ErrNotFound
+	}
+
+	return document.version, document.decode(target)
+}
+
+// each calls visit for every document until it returns false or an error.
+func (d *memoryDocuments) each(visit func(memoryDocument) (bool, error)) error {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	for _, document := range d.documents {
+		next, err := visit(document)
+		if err != nil {
+			return err
+		}
+
+		if !next {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func (d *memoryDocuments) save(key uuid.UUID, value interface{}, version Version) (Version, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return Version{}, err
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	current, ok := d.documents[key]
+	if ok == version.IsZero() || current.version != version {
+		return Version{}, ErrConflict
+	}
+
+	next := Version{SeqNo: current.version.SeqNo + 1, PrimaryTerm: 1}
+	d.documents[key] = memoryDocument{data: data, version: next}
+	return next, nil
+}
+
+func (d *memoryDocuments) remove(key uuid.UUID, version Version) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	current, ok := d.documents[key]
+	if !ok {
+		return ErrNotFound
+	}
+
+	if current.version != version {
+		return ErrConflict
+	}
+
+	delete(d.documents, key)
+	return nil
+}
+
+// removeAll deletes the documents match reports.
+func (d *memoryDocuments) removeAll(match func(memoryDocument) (bool, error)) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for key, document := range d.documents {
+		matched, err := match(document)
+		if err != nil {
+			return err
+		}
+
+		if matched {
+			delete(d.documents, key)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,157 @@
+package management
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
+)
+
+// Principal is the authenticated caller placed into the request locals by
+// Authenticate.
+type Principal struct {
+	Organization Organization
+	Account      Account
+	Claims       Claims
+	// APIKey is set instead of Account and Claims for integrations.
+	APIKey *APIKey
+}
+
+// Verified reports whether the caller may publish campaigns. API keys are only
+// created by verified accounts.
+func (p Principal) Verified() bool {
+	return p.APIKey != nil || p.Account.Verified
+}
+
+const principalKey = "principal"
+
+func principal(c *fiber.Ctx) Principal {
+	value, _ := c.Locals(principalKey).(Principal)
+	return value
+}
+
+// Authenticate validates the session cookie, including its signing method,
+// expiry, issuer and revocation, and loads the organization and account it was
+// issued for. Handlers mounted after it read the caller through principal.
+func (s *server) Authenticate(c *fiber.Ctx) error {
+	var claims Claims
+	if _, err := jwt.ParseWithClaims(c.Cookies(s.configuration.Cookie), &claims, s.keyring.key); err != nil {
+		return fiber.NewError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	if !claims.VerifyIssuer(s.configuration.Issuer, true) || claims.ExpiresAt == 0 {
+		return fiber.NewError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	token, err := uuid.Parse(claims.Id)
+	if err != nil {
+		return fiber.NewError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	revoked, err := s.revocations.IsRevoked(c.UserContext(), token)
+	if err != nil {
+		return storageError(err)
+	}
+
+	if revoked {
+		return fiber.NewError(http.StatusUnauthorized, "token revoked")
+	}
+
+	organization, err := s.organizations.FindByKey(c.UserContext(), claims.Organization)
+	if errors.Is(err, ErrNotFound) {
+		return fiber.NewError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	if err != nil {
+		return storageError(err)
+	}
+
+	account, ok := organization.AccountByKey(claims.Account)
+	if !ok {
+		return fiber.NewError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	c.Locals(principalKey, Principal{Organization: organization, Account: account, Claims: claims})
+	return c.Next()
+}
+
+// AuthenticateKey accepts an API key presented as a bearer token in the
+// Authorization header and falls back to the session cookie of Authenticate
+// otherwise. Routes using it pass the scopes keys need to Require.
+func (s *server) AuthenticateKey(c *fiber.Ctx) error {
+	header := c.Get(fiber.HeaderAuthorization)
+	if header == "" {
+		return s.Authenticate(c)
+	}
+
+	token := strings.TrimPrefix(header, "Bearer ")
+	if token == header {
+		return fiber.NewError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	key, value, ok := split(token)
+	if !ok {
+		return fiber.NewError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	organization, err := s.organizations.FindByAPIKey(c.UserContext(), key)
+	if errors.Is(err, ErrNotFound) {
+		return fiber.NewError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	if err != nil {
+		return storageError(err)
+	}
+
+	apiKey, ok := organization.APIKey(key)
+	if !ok || subtle.ConstantTimeCompare(apiKey.Secret, hash(value)) != 1 {
+		return fiber.NewError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	if !apiKey.Expires.IsZero() && time.Now().After(apiKey.Expires) {
+		return fiber.NewError(http.StatusUnauthorized, "api key expired")
+	}
+
+	c.Locals(principalKey, Principal{Organization: organization, APIKey: &apiKey})
+	return c.Next()
+}
+
+// CSRF enforces the double-submit token on state-changing requests made with
+// the session cookies: the CSRFHeader has to repeat the CSRFCookie, which only
+// scripts of an allowed origin can read. Requests without session cookies,
+// such as logins or bearer authenticated integrations, are not exposed.
+func (s *server) CSRF(c *fiber.Ctx) error {
+	switch c.Method() {
+	case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+		return c.Next()
+	}
+
+	if c.Cookies(s.configuration.Cookie) == "" && c.Cookies(s.configuration.RefreshCookie) == "" {
+		return c.Next()
+	}
+
+	token := c.Cookies(s.configuration.CSRFCookie)
+	if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(c.Get(s.configuration.CSRFHeader))) != 1 {
+		return fiber.NewError(http.StatusForbidden, "invalid csrf token")
+	}
+
+	return c.Next()
+}
+
+// csrf sets a new double-submit token readable by scripts for the session.
+func (s *server) csrf(c *fiber.Ctx, expires time.Time) error {
+	token, _, err := secret()
+	if err != nil {
+		return err
+	}
+
+	cookie := s.policy(s.configuration.CSRFCookie, token, expires)
+	cookie.HTTPOnly = false
+	c.Cookie(cookie)
+	return nil
+}
@@ -0,0 +1,102 @@
+package management
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// forge sends a request with the cookies of client, as a browser does for
+// another site, and header as the CSRF header unless it is empty.
+func (c *testClient) forge(method, path, body, header string) int {
+	c.t.Helper()
+	request := httptest.NewRequest(method, path, strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	for name, value := range c.cookies {
+		request.AddCookie(&http.Cookie{Name: name, Value: value})
+	}
+
+	if header != "" {
+		request.Header.Set(c.server.config.CSRFHeader, header)
+	}
+
+	response, err := c.server.app.Test(request, -1)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	response.Body.Close()
+
+	return response.StatusCode
+}
+
+func TestCSRF(t *testing.T) {
+	s := newTestServer(t)
+	client := s.register(t, "owner@example.com", "Acme")
+	csrf := client.cookies[s.config.CSRFCookie]
+
+	for _, request := range []struct{ method, path, body string }{
+		{"POST", "/account/refresh", ""},
+		{"POST", "/account/logout", ""},
+		{"POST", "/campaign/create", `{"name":"Forged"}`},
+		{"PATCH", "/account/password", `{"old":"secret1","new":"forged1"}`},
+		{"DELETE", "/campaign/remove/00000000-0000-0000-0000-000000000000", ""},
+	} {
+		for _, header := range []string{"", "forged", csrf + "x"} {
+			if status := client.forge(request.method, request.path, request.body, header); status != http.StatusForbidden {
+				t.Errorf("%s %s with header %q: got %d, want 403", request.method, request.path, header, status)
+			}
+		}
+	}
+
+	// reads are not protected and the session survived the forged requests
+	if status := client.forge("GET", "/account/authorize", "", ""); status != http.StatusOK {
+		t.Fatalf("got %d, want 200", status)
+	}
+
+	var campaigns []Campaign
+	decode(t, client.expect(http.StatusOK, "GET", "/campaigns", ""), &campaigns)
+	if len(campaigns) != 0 {
+		t.Fatalf("forged campaign created %+v", campaigns)
+	}
+
+	if status := client.forge("POST", "/account/refresh", "", csrf); status != http.StatusOK {
+		t.Fatalf("got %d with the token, want 200", status)
+	}
+}
+
+func TestCSRFWithoutSession(t *testing.T) {
+	s := newTestServer(t)
+	s.register(t, "owner@example.com", "Acme")
+
+	// requests without session cookies carry no ambient authority
+	client := s.client(t)
+	client.cookies[s.config.CSRFCookie] = "token"
+	if status := client.forge("POST", "/account/login", `{"email":"owner@example.com","password":"secret1"}`, ""); status != http.StatusOK {
+		t.Fatalf("login without session cookies got %d", status)
+	}
+
+	if status := s.client(t).forge("POST", "/account/forgot", `{"email":"owner@example.com"}`, ""); status != http.StatusOK {
+		t.Fatalf("got %d, want 200", status)
+	}
+
+	// a session cookie without the CSRF cookie is refused
+	client = s.register(t, "other@example.com", "Other")
+	delete(client.cookies, s.config.CSRFCookie)
+	if status := client.forge("POST", "/account/refresh", "", ""); status != http.StatusForbidden {
+		t.Fatalf("got %d, want 403", status)
+	}
+}
+
+func TestLogoutMethod(t *testing.T) {
+	s := newTestServer(t)
+	client := s.register(t, "owner@example.com", "Acme")
+
+	// a link or redirect of another site cannot sign the user out
+	if status := client.forge("GET", "/account/logout", "", ""); status == http.StatusOK {
+		t.Fatal("logout accepted a GET")
+	}
+
+	client.expect(http.StatusOK, "GET", "/account/authorize", "")
+	client.expect(http.StatusOK, "POST", "/account/refresh", "")
+}
@@ -1,22 +1,582 @@
 package management
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/query"
 	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/google/uuid"
 )
 
-func Migrate(s *elasticsearch.Client, c Config) error {
-	response, err := s.Indices.Exists([]string{c.Index})
+// migrations are applied in order, each once, and recorded in the
+// Config.Migrations index. An applied migration must not change, so it uses
+// the mapping snapshots of its version and a new schema needs a new migration.
+var migrations = []migration{
+	{Version: 1, Name: "index templates", Apply: func(ctx context.Context, m *migrator) error {
+		for alias, mapping := range mappings1(m.configuration) {
+			if err := m.template(ctx, alias, mapping); err != nil {
+				return err
+			}
+		}
+		return nil
+	}},
+	{Version: 2, Name: "aliased indices", Apply: func(ctx context.Context, m *migrator) error {
+		for alias := range mappings1(m.configuration) {
+			if err := m.reindex(ctx, alias); err != nil {
+				return err
+			}
+		}
+		return nil
+	}},
+	{Version: 3, Name: "split campaigns", Apply: func(ctx context.Context, m *migrator) error {
+		return splitCampaigns(ctx, m.storage, m.configuration)
+	}},
+	{Version: 4, Name: "ticket email", Apply: func(ctx context.Context, m *migrator) error {
+		if err := m.template(ctx, m.configuration.Tickets, ticketsMapping4); err != nil {
+			return err
+		}
+
+		return m.putMapping(ctx, m.configuration.Tickets, `{ "properties": { "email": { "type": "keyword", "normalizer": "lowercase" } } }`)
+	}},
+}
+
+const (
+	migrationLockID = "lock"
+	migrationLease  = 10 * time.Minute
+	migrationPoll   = time.Second
+)
+
+var errLockLost = errors.New("migration lock lost to another instance")
+
+type migration struct {
+	Version int
+	Name    string
+	Apply   func(ctx context.Context, m *migrator) error
+}
+
+type migrationRecord struct {
+	Version int       `json:"version"`
+	Name    string    `json:"name"`
+	Applied time.Time `json:"applied"`
+}
+
+// migrationLock is held by the instance applying migrations. It expires, so an
+// instance that died while holding it only blocks the others for a lease.
+type migrationLock struct {
+	Owner   uuid.UUID `json:"owner"`
+	Expires time.Time `json:"expires"`
+}
+
+type migrator struct {
+	storage       *elasticsearch.Client
+	configuration Config
+	documents     elasticDocuments
+	owner         uuid.UUID
+	lease         Version
+	version       int
+}
+
+// Migrate applies the pending migrations. Instances starting together wait for
+// the one holding the lock and then find nothing left to apply. The lease of
+// the lock is renewed while migrations run, and they are cancelled once it is
+// lost.
+func Migrate(s *elasticsearch.Client, c Config) (err error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m := &migrator{
+		storage:       s,
+		configuration: c,
+		documents:     elasticDocuments{storage: s, index: c.Migrations},
+		owner:         uuid.New(),
+	}
+
+	if err = m.create(ctx, c.Migrations, migrationsMapping); err != nil {
+		return err
+	}
+
+	if err = m.lock(ctx); err != nil {
+		return err
+	}
+
+	renewal := make(chan error, 1)
+	go func() { renewal <- m.keep(ctx, cancel) }()
+
+	defer func() {
+		cancel()
+		if lost := <-renewal; lost != nil {
+			err = fmt.Errorf("migrations: %w", lost)
+			return
+		}
+
+		// A lock that cannot be released expires with its lease.
+		if err := m.documents.remove(context.Background(), migrationLockID, m.lease); err != nil {
+			log.Printf("migrations: release lock: %v", err)
+		}
+	}()
+
+	for _, migration := range migrations {
+		id := strconv.Itoa(migration.Version)
+		_, err := m.documents.get(ctx, id, &migrationRecord{})
+		if err == nil {
+			continue
+		}
+
+		if !errors.Is(err, ErrNotFound) {
+			return err
+		}
+
+		m.version = migration.Version
+		if err = migration.Apply(ctx, m); err != nil {
+			return fmt.Errorf("migration %d (%s): %w", migration.Version, migration.Name, err)
+		}
+
+		record := migrationRecord{Version: migration.Version, Name: migration.Name, Applied: time.Now()}
+		if _, err = m.documents.save(ctx, id, record, Version{}); err != nil {
+			return err
+		}
+	}
+
+	for alias, mapping := range mappings(c) {
+		if err = m.verify(ctx, alias, mapping); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// lock waits until it holds the migration lock, taking it over once expired.
+func (m *migrator) lock(ctx context.Context) error {
+	for {
+		lease, err := m.documents.save(ctx, migrationLockID, m.claim(), Version{})
+		if !errors.Is(err, ErrConflict) {
+			m.lease = lease
+			return err
+		}
+
+		var held migrationLock
+		lease, err = m.documents.get(ctx, migrationLockID, &held)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if time.Now().After(held.Expires) {
+			lease, err = m.documents.save(ctx, migrationLockID, m.claim(), lease)
+			if !errors.Is(err, ErrConflict) {
+				m.lease = lease
+				return err
+			}
+		}
+
+		time.Sleep(migrationPoll)
+	}
+}
+
+// keep renews the lease of the lock until ctx is done. It cancels ctx and
+// returns the error once the lock is lost, or cannot be renewed before its
+// lease runs out.
+func (m *migrator) keep(ctx context.Context, cancel context.CancelFunc) error {
+	ticker := time.NewTicker(migrationLease / 3)
+	defer ticker.Stop()
+
+	expires := time.Now().Add(migrationLease)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		next := time.Now().Add(migrationLease)
+		err := m.renew(ctx)
+		if err == nil {
+			expires = next
+			continue
+		}
+
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if errors.Is(err, errLockLost) || time.Now().Add(migrationLease/3).After(expires) {
+			cancel()
+			return err
+		}
+
+		log.Printf("migrations: renew lock: %v", err)
+	}
+}
+
+// renew extends the lease of the lock, failing if another instance took it
+// over meanwhile.
+func (m *migrator) renew(ctx context.Context) error {
+	lease, err := m.documents.save(ctx, migrationLockID, m.claim(), m.lease)
+	if errors.Is(err, ErrConflict) {
+		return errLockLost
+	}
+
+	m.lease = lease
+	return err
+}
+
+func (m *migrator) claim() migrationLock {
+	return migrationLock{Owner: m.owner, Expires: time.Now().Add(migrationLease)}
+}
+
+// create creates index with mapping unless it exists already.
+func (m *migrator) create(ctx context.Context, index, mapping string) error {
+	if exists, err := m.exists(ctx, index); exists || err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"mappings": json.RawMessage(mapping)})
 	if err != nil {
 		return err
 	}
 
-	if response.StatusCode == 200 {
+	err = result(m.storage.Indices.Create(index,
+		m.storage.Indices.Create.WithContext(ctx),
+		m.storage.Indices.Create.WithBody(bytes.NewReader(body)),
+	))
+	if err != nil {
+		// Another instance may have created it first.
+		if exists, _ := m.exists(ctx, index); exists {
+			return nil
+		}
+	}
+
+	return err
+}
+
+func (m *migrator) exists(ctx context.Context, index string) (bool, error) {
+	response, err := m.storage.Indices.Exists([]string{index}, m.storage.Indices.Exists.WithContext(ctx))
+	if err != nil {
+		return false, err
+	}
+	response.Body.Close()
+
+	return response.StatusCode == http.StatusOK, nil
+}
+
+// template applies mapping to every index created for alias by reindex.
+func (m *migrator) template(ctx context.Context, alias, mapping string) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"index_patterns": []string{alias + "-*"},
+		"template":       map[string]interface{}{"mappings": json.RawMessage(mapping)},
+	})
+	if err != nil {
+		return err
+	}
+
+	return result(m.storage.Indices.PutIndexTemplate(alias, bytes.NewReader(body), m.storage.Indices.PutIndexTemplate.WithContext(ctx)))
+}
+
+// putMapping adds fields to the indices behind alias, changing the mapping of
+// an existing field needs a reindex instead.
+func (m *migrator) putMapping(ctx context.Context, alias, mapping string) error {
+	return result(m.storage.Indices.PutMapping([]string{alias}, strings.NewReader(mapping), m.storage.Indices.PutMapping.WithContext(ctx)))
+}
+
+// verify fails unless the indices behind alias map the fields of mapping with
+// the same types, since term and nested lookups silently miss or fail on a
+// field mapped dynamically.
+func (m *migrator) verify(ctx context.Context, alias, mapping string) error {
+	response, err := m.storage.Indices.GetMapping(
+		m.storage.Indices.GetMapping.WithContext(ctx),
+		m.storage.Indices.GetMapping.WithIndex(alias),
+	)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.IsError() {
+		return fmt.Errorf("elasticsearch: %s", response.String())
+	}
+
+	var payload map[string]struct {
+		Mappings json.RawMessage `json:"mappings"`
+	}
+
+	if err = json.NewDecoder(response.Body).Decode(&payload); err != nil {
+		return err
+	}
+
+	for index, live := range payload {
+		if err = compareMapping(index, []byte(mapping), live.Mappings); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// fieldMapping is the part of a field mapping an index has to match.
+type fieldMapping struct {
+	Type       string                  `json:"type"`
+	Normalizer string                  `json:"normalizer"`
+	Properties map[string]fieldMapping `json:"properties"`
+}
+
+// kind is the type of the field, which Elasticsearch omits for objects.
+func (f fieldMapping) kind() string {
+	if f.Type == "" {
+		return "object"
+	}
+
+	return f.Type
+}
+
+// compareMapping compares the types and normalizers of the fields of want,
+// including those of objects and nested fields, with the live mapping of index.
+func compareMapping(index string, want, live []byte) error {
+	var expected, actual fieldMapping
+	if err := json.Unmarshal(want, &expected); err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(live, &actual); err != nil {
+		return err
+	}
+
+	return compareProperties(index, "", expected.Properties, actual.Properties)
+}
+
+func compareProperties(index, prefix string, want, live map[string]fieldMapping) error {
+	for name, field := range want {
+		mapped, ok := live[name]
+		if !ok {
+			return fmt.Errorf("index %s does not map %s%s, reindex it with the current mapping", index, prefix, name)
+		}
+
+		if mapped.kind() != field.kind() {
+			return fmt.Errorf("index %s maps %s%s as %s instead of %s, reindex it with the current mapping", index, prefix, name, mapped.kind(), field.kind())
+		}
+
+		if mapped.Normalizer != field.Normalizer {
+			return fmt.Errorf("index %s normalizes %s%s with %q instead of %q, reindex it with the current mapping", index, prefix, name, mapped.Normalizer, field.Normalizer)
+		}
+
+		if err := compareProperties(index, prefix+name+".", field.Properties, mapped.Properties); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// reindex copies the documents of alias into a new index named after the
+// migration, created from the template, and atomically points alias to it
+// while deleting the previous index. A plain index of the same name is
+// replaced by the alias the same way. The previous index is blocked for writes
+// while it is copied, so instances still serving fail them instead of losing
+// them, and unblocked again if the reindex fails.
+func (m *migrator) reindex(ctx context.Context, alias string) (err error) {
+	target := fmt.Sprintf("%s-%d", alias, m.version)
+	sources, err := m.indices(ctx, alias)
+	if err != nil {
+		return err
+	}
+
+	if len(sources) == 1 && sources[0] == target {
 		return nil
 	}
 
-	if _, err = s.Indices.Create(c.Index); err != nil {
+	if len(sources) > 0 {
+		if err = m.block(ctx, sources, true); err != nil {
+			return err
+		}
+
+		defer func() {
+			if err == nil {
+				return
+			}
+
+			if err := m.block(context.Background(), sources, false); err != nil {
+				log.Printf("migrations: unblock %s: %v", strings.Join(sources, ", "), err)
+			}
+		}()
+	}
+
+	// A target left by an interrupted run may be incomplete.
+	err = result(m.storage.Indices.Delete([]string{target},
+		m.storage.Indices.Delete.WithContext(ctx),
+		m.storage.Indices.Delete.WithIgnoreUnavailable(true),
+	))
+	if err != nil {
+		return err
+	}
+
+	if err = result(m.storage.Indices.Create(target, m.storage.Indices.Create.WithContext(ctx))); err != nil {
+		return err
+	}
+
+	var actions []map[string]interface{}
+	for _, source := range sources {
+		if err = m.copy(ctx, source, target); err != nil {
+			return err
+		}
+
+		actions = append(actions, map[string]interface{}{"remove_index": map[string]string{"index": source}})
+	}
+	actions = append(actions, map[string]interface{}{"add": map[string]string{"index": target, "alias": alias}})
+
+	body, err := json.Marshal(map[string]interface{}{"actions": actions})
+	if err != nil {
+		return err
+	}
+
+	return result(m.storage.Indices.UpdateAliases(bytes.NewReader(body), m.storage.Indices.UpdateAliases.WithContext(ctx)))
+}
+
+// block sets or clears the write block of indices.
+func (m *migrator) block(ctx context.Context, indices []string, blocked bool) error {
+	var value interface{}
+	if blocked {
+		value = true
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"index.blocks.write": value})
+	if err != nil {
 		return err
 	}
 
+	return result(m.storage.Indices.PutSettings(bytes.NewReader(body),
+		m.storage.Indices.PutSettings.WithContext(ctx),
+		m.storage.Indices.PutSettings.WithIndex(indices...),
+	))
+}
+
+func (m *migrator) copy(ctx context.Context, source, target string) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"source": map[string]string{"index": source},
+		"dest":   map[string]string{"index": target},
+	})
+	if err != nil {
+		return err
+	}
+
+	response, err := m.storage.Reindex(bytes.NewReader(body),
+		m.storage.Reindex.WithContext(ctx),
+		m.storage.Reindex.WithWaitForCompletion(true),
+		m.storage.Reindex.WithRefresh(true),
+	)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.IsError() {
+		return fmt.Errorf("elasticsearch: %s", response.String())
+	}
+
+	var payload struct {
+		Failures []json.RawMessage `json:"failures"`
+	}
+
+	if err = json.NewDecoder(response.Body).Decode(&payload); err != nil {
+		return err
+	}
+
+	if len(payload.Failures) > 0 {
+		return fmt.Errorf("elasticsearch: reindex %s into %s: %s", source, target, payload.Failures[0])
+	}
+
+	return nil
+}
+
+// indices returns the indices behind alias, the index itself if it is not an
+// alias, or none if it does not exist.
+func (m *migrator) indices(ctx context.Context, alias string) ([]string, error) {
+	response, err := m.storage.Indices.Get([]string{alias}, m.storage.Indices.Get.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+
+	if response.IsError() {
+		return nil, fmt.Errorf("elasticsearch: %s", response.String())
+	}
+
+	var payload map[string]json.RawMessage
+	if err = json.NewDecoder(response.Body).Decode(&payload); err != nil {
+		return nil, err
+	}
+
+	indices := make([]string, 0, len(payload))
+	for index := range payload {
+		indices = append(indices, index)
+	}
+
+	return indices, nil
+}
+
+func result(response *esapi.Response, err error) error {
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.IsError() {
+		return fmt.Errorf("elasticsearch: %s", response.String())
+	}
+
 	return nil
 }
+
+// splitCampaigns moves the campaigns still embedded in organizations into the
+// campaigns index. Campaigns already copied by an interrupted run are kept, so
+// it can safely run again.
+func splitCampaigns(ctx context.Context, s *elasticsearch.Client, c Config) error {
+	organizations := elasticDocuments{storage: s, index: c.Index}
+	campaigns := elasticDocuments{storage: s, index: c.Campaigns}
+
+	for {
+		hits, err := organizations.search(ctx, query.Search{Query: query.Nested("campaigns", query.Exists("campaigns.key"))}, elasticMaxResults)
+		if err != nil || len(hits) == 0 {
+			return err
+		}
+
+		for _, hit := range hits {
+			var organization Organization
+			if err = json.Unmarshal(hit.Source, &organization); err != nil {
+				return err
+			}
+
+			for _, campaign := range organization.Campaigns {
+				campaign.Organization = organization.Key
+				if _, err = campaigns.save(ctx, campaign.Key.String(), campaign, Version{}); err != nil && !errors.Is(err, ErrConflict) {
+					return err
+				}
+			}
+
+			organization.Campaigns = nil
+			if _, err = organizations.save(ctx, organization.Key.String(), organization, hit.version()); err != nil && !errors.Is(err, ErrConflict) {
+				return err
+			}
+		}
+
+		if err = result(s.Indices.Refresh(s.Indices.Refresh.WithContext(ctx), s.Indices.Refresh.WithIndex(c.Index))); err != nil {
+			return err
+		}
+	}
+}
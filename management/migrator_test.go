@@ -0,0 +1,162 @@
+package management
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+func TestCompareMapping(t *testing.T) {
+	// change returns the current mapping with old replaced by new.
+	change := func(old, new string) string {
+		if !strings.Contains(organizationsMapping, old) {
+			t.Fatalf("mapping does not contain %s", old)
+		}
+
+		return strings.Replace(organizationsMapping, old, new, 1)
+	}
+
+	email := `"email": { "type": "keyword", "normalizer": "lowercase" },`
+	tests := []struct {
+		name  string
+		live  string
+		valid bool
+	}{
+		{"current", organizationsMapping, true},
+		{"extra field", change(`"created": { "type": "date" }
+		}`, `"created": { "type": "date" }, "other": { "type": "text" }
+		}`), true},
+		{"extra nested field", change(email, email+` "nickname": { "type": "text" },`), true},
+		{"dynamic", `{"properties":{"key":{"type":"text"},"name":{"type":"text"},"accounts":{"properties":{}},"campaigns":{"properties":{}},"keys":{"properties":{}},"created":{"type":"date"}}}`, false},
+		{"accounts not nested", change(`"type": "nested",`, ``), false},
+		{"nested email as text", change(email, `"email": { "type": "text" },`), false},
+		{"nested email without normalizer", change(email, `"email": { "type": "keyword" },`), false},
+		{"name without normalizer", change(`"name": { "type": "keyword", "normalizer": "lowercase" }`, `"name": { "type": "keyword" }`), false},
+		{"missing nested field", change(`"subject": { "type": "keyword" },`, ``), false},
+		{"missing object field", change(`"scopes": { "type": "keyword" },`, ``), false},
+		{"missing field", `{"properties":{"key":{"type":"keyword"}}}`, false},
+		{"empty", `{}`, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := compareMapping("organizations", []byte(organizationsMapping), []byte(test.live)); (err == nil) != test.valid {
+				t.Fatalf("got %v, want valid %v", err, test.valid)
+			}
+		})
+	}
+}
+
+// testCluster records the requests an Elasticsearch client sends, answering
+// _reindex with the reindex status.
+type testCluster struct {
+	mutex    sync.Mutex
+	requests []string
+	reindex  int
+}
+
+func (c *testCluster) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	c.mutex.Lock()
+	c.requests = append(c.requests, strings.TrimSpace(r.Method+" "+r.URL.Path+" "+string(body)))
+	c.mutex.Unlock()
+
+	w.Header().Set("X-Elastic-Product", "Elasticsearch")
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/tickets":
+		w.Write([]byte(`{"tickets":{}}`))
+	case r.URL.Path == "/_reindex":
+		w.WriteHeader(c.reindex)
+		w.Write([]byte(`{"failures":[]}`))
+	default:
+		w.Write([]byte(`{"acknowledged":true}`))
+	}
+}
+
+func testMigrator(t *testing.T, cluster *testCluster) *migrator {
+	t.Helper()
+	server := httptest.NewServer(cluster)
+	t.Cleanup(server.Close)
+
+	client, err := elasticsearch.NewClient(elasticsearch.Config{Addresses: []string{server.URL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &migrator{storage: client, version: 2}
+}
+
+func TestReindex(t *testing.T) {
+	cluster := &testCluster{reindex: http.StatusOK}
+	if err := testMigrator(t, cluster).reindex(context.Background(), "tickets"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"GET /tickets",
+		`PUT /tickets/_settings {"index.blocks.write":true}`,
+		"DELETE /tickets-2",
+		"PUT /tickets-2",
+		`POST /_reindex {"dest":{"index":"tickets-2"},"source":{"index":"tickets"}}`,
+		`POST /_aliases {"actions":[{"remove_index":{"index":"tickets"}},{"add":{"alias":"tickets","index":"tickets-2"}}]}`,
+	}
+
+	if strings.Join(cluster.requests, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("got requests\n%s\nwant\n%s", strings.Join(cluster.requests, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestReindexFailure(t *testing.T) {
+	cluster := &testCluster{reindex: http.StatusInternalServerError}
+	if err := testMigrator(t, cluster).reindex(context.Background(), "tickets"); err == nil {
+		t.Fatal("reindex failure ignored")
+	}
+
+	last := cluster.requests[len(cluster.requests)-1]
+	if last != `PUT /tickets/_settings {"index.blocks.write":null}` {
+		t.Fatalf("source left blocked, last request %s", last)
+	}
+}
+
+func TestMigrationSnapshots(t *testing.T) {
+	t.Setenv("MODE", ModeDevelopment)
+	config, err := NewConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// template returns the index template the migration puts for tickets.
+	template := func(version int) string {
+		cluster := &testCluster{reindex: http.StatusOK}
+		m := testMigrator(t, cluster)
+		m.configuration, m.version = config, version
+		if err := migrations[version-1].Apply(context.Background(), m); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, request := range cluster.requests {
+			if strings.HasPrefix(request, "PUT /_index_template/"+config.Tickets+" ") {
+				return request
+			}
+		}
+
+		t.Fatalf("migration %d put no template for %s", version, config.Tickets)
+		return ""
+	}
+
+	// tickets only map email since migration 4
+	if strings.Contains(template(1), `"email"`) {
+		t.Fatal("migration 1 uses the current tickets mapping")
+	}
+
+	if !strings.Contains(template(4), `"email"`) {
+		t.Fatal("migration 4 does not map the ticket email")
+	}
+}
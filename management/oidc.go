@@ -0,0 +1,448 @@
+package management
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	oidcCookie   = "oidc"
+	oidcLifetime = 10 * time.Minute
+	oidcLeeway   = time.Minute
+)
+
+// oidcProvider runs the authorization code flow with PKCE against the
+// identity provider of Config.OIDCIssuer. Its discovery document is fetched
+// once and its signing keys whenever an unknown key id shows up.
+type oidcProvider struct {
+	issuer   string
+	client   string
+	secret   string
+	redirect string
+	scopes   string
+	http     *http.Client
+
+	mutex     sync.Mutex
+	discovery *oidcDiscovery
+	keys      map[string]*rsa.PublicKey
+	fetched   time.Time
+}
+
+type oidcDiscovery struct {
+	Issuer        string `json:"issuer"`
+	Authorization string `json:"authorization_endpoint"`
+	Token         string `json:"token_endpoint"`
+	JWKS          string `json:"jwks_uri"`
+}
+
+// oidcClaims are the claims of an ID token the flow relies on.
+type oidcClaims struct {
+	Issuer        string       `json:"iss"`
+	Subject       string       `json:"sub"`
+	Audience      oidcAudience `json:"aud"`
+	ExpiresAt     int64        `json:"exp"`
+	IssuedAt      int64        `json:"iat"`
+	Nonce         string       `json:"nonce"`
+	Email         string       `json:"email"`
+	EmailVerified bool         `json:"email_verified"`
+}
+
+func (c oidcClaims) Valid() error {
+	if time.Now().After(time.Unix(c.ExpiresAt, 0).Add(oidcLeeway)) {
+		return errors.New("id token expired")
+	}
+
+	if time.Unix(c.IssuedAt, 0).After(time.Now().Add(oidcLeeway)) {
+		return errors.New("id token issued in the future")
+	}
+
+	return nil
+}
+
+// oidcAudience accepts both forms of the aud claim, a string or an array.
+type oidcAudience []string
+
+func (a *oidcAudience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = oidcAudience{single}
+		return nil
+	}
+
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return err
+	}
+
+	*a = multiple
+	return nil
+}
+
+func newOIDCProvider(c Config, client *http.Client) *oidcProvider {
+	if c.OIDCIssuer == "" {
+		return nil
+	}
+
+	return &oidcProvider{
+		issuer:   strings.TrimSuffix(c.OIDCIssuer, "/"),
+		client:   c.OIDCClient,
+		secret:   c.OIDCSecret,
+		redirect: c.OIDCRedirect,
+		scopes:   c.OIDCScopes,
+		http:     client,
+		keys:     map[string]*rsa.PublicKey{},
+	}
+}
+
+// @Summary OIDCLogin
+// @Schemes
+// @Description Redirect to the identity provider to sign in with single sign-on
+// @Tags account
+// @Success 302
+// @Failure 404
+// @Failure 503
+// @Router /account/oidc/login [get]
+func (s *server) OIDCLogin(c *fiber.Ctx) error {
+	if s.oidc == nil {
+		return fiber.NewError(http.StatusNotFound, "single sign-on is not configured")
+	}
+
+	discovery, err := s.oidc.discover(c.UserContext())
+	if err != nil {
+		return fiber.NewError(http.StatusServiceUnavailable, err.Error())
+	}
+
+	state, _, err := secret()
+	if err != nil {
+		return err
+	}
+
+	nonce, _, err := secret()
+	if err != nil {
+		return err
+	}
+
+	verifier, _, err := secret()
+	if err != nil {
+		return err
+	}
+
+	s.oidcCookie(c, state+"."+nonce+"."+verifier, time.Now().Add(oidcLifetime))
+
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {s.oidc.client},
+		"redirect_uri":          {s.oidc.redirect},
+		"scope":                 {s.oidc.scopes},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(hash(verifier))},
+		"code_challenge_method": {"S256"},
+	}
+
+	return c.Redirect(discovery.Authorization+"?"+query.Encode(), http.StatusFound)
+}
+
+// @Summary OIDCCallback
+// @Schemes
+// @Description Complete single sign-on, signing in the verified account with the verified email of the identity or, with two-factor authentication enabled, redirecting to complete a login challenge
+// @Tags account
+// @Param code query string true "authorization code"
+// @Param state query string true "state"
+// @Success 302
+// @Failure 400
+// @Failure 403
+// @Failure 404
+// @Failure 503
+// @Router /account/oidc/callback [get]
+func (s *server) OIDCCallback(c *fiber.Ctx) error {
+	if s.oidc == nil {
+		return fiber.NewError(http.StatusNotFound, "single sign-on is not configured")
+	}
+
+	flow := strings.Split(c.Cookies(oidcCookie), ".")
+	s.oidcCookie(c, "", time.Now().Add(-time.Second))
+
+	if problem := c.Query("error"); problem != "" {
+		return fiber.NewError(http.StatusBadRequest, "single sign-on failed: "+problem)
+	}
+
+	if len(flow) != 3 || subtle.ConstantTimeCompare([]byte(flow[0]), []byte(c.Query("state"))) != 1 {
+		return fiber.NewError(http.StatusBadRequest, "invalid state")
+	}
+
+	token, err := s.oidc.exchange(c.UserContext(), c.Query("code"), flow[2])
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, "single sign-on failed: "+err.Error())
+	}
+
+	claims, err := s.oidc.verify(c.UserContext(), token)
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, "single sign-on failed: "+err.Error())
+	}
+
+	if subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(flow[1])) != 1 {
+		return fiber.NewError(http.StatusBadRequest, "invalid nonce")
+	}
+
+	if claims.Email == "" || !claims.EmailVerified {
+		return fiber.NewError(http.StatusForbidden, "identity provider did not verify the email")
+	}
+
+	organization, err := s.organizations.FindByEmail(c.UserContext(), claims.Email)
+	if errors.Is(err, ErrNotFound) {
+		return fiber.NewError(http.StatusForbidden, "no account for this identity, ask your organization for an invitation")
+	}
+
+	if err != nil {
+		return storageError(err)
+	}
+
+	account, ok := organization.AccountByEmail(claims.Email)
+	if !ok {
+		return fiber.NewError(http.StatusForbidden, "no account for this identity, ask your organization for an invitation")
+	}
+
+	if account.Subject != "" && account.Subject != claims.Subject {
+		return fiber.NewError(http.StatusForbidden, "account is linked to another identity")
+	}
+
+	// Anyone may have registered an unverified account with the address, the
+	// identity would be signed into it and linked while its password stays
+	// with whoever registered it.
+	if !account.Verified {
+		return fiber.NewError(http.StatusForbidden, "verify the email of your account before using single sign-on")
+	}
+
+	if account.Subject == "" {
+		organization, err = s.modifyAccount(c, organization, account.Key, func(account *Account) error {
+			if account.Subject != "" && account.Subject != claims.Subject {
+				return fiber.NewError(http.StatusForbidden, "account is linked to another identity")
+			}
+
+			account.Subject = claims.Subject
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		account, _ = organization.AccountByKey(account.Key)
+	}
+
+	// the identity provider vouches for the email only, a second factor of
+	// the account is still asked for
+	if account.TOTP.Enabled() {
+		challenge, err := s.loginTicket(c, organization, account)
+		if err != nil {
+			return err
+		}
+
+		return c.Redirect(s.configuration.URL+"/login/verify?token="+url.QueryEscape(challenge.Token), http.StatusFound)
+	}
+
+	if err = s.login(c, organization, account); err != nil {
+		return err
+	}
+
+	return c.Redirect(s.configuration.URL, http.StatusFound)
+}
+
+// oidcCookie keeps the flow away from scripts and, since the identity provider
+// redirects back from another site, is never sent strict.
+func (s *server) oidcCookie(c *fiber.Ctx, value string, expires time.Time) {
+	cookie := s.policy(oidcCookie, value, expires)
+	cookie.HTTPOnly = true
+	if strings.EqualFold(cookie.SameSite, "strict") {
+		cookie.SameSite = "lax"
+	}
+
+	c.Cookie(cookie)
+}
+
+func (p *oidcProvider) discover(ctx context.Context) (oidcDiscovery, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.discovery != nil {
+		return *p.discovery, nil
+	}
+
+	var discovery oidcDiscovery
+	if err := p.get(ctx, p.issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+		return oidcDiscovery{}, err
+	}
+
+	if strings.TrimSuffix(discovery.Issuer, "/") != p.issuer {
+		return oidcDiscovery{}, fmt.Errorf("identity provider reported issuer %q", discovery.Issuer)
+	}
+
+	p.discovery = &discovery
+	return discovery, nil
+}
+
+// exchange redeems the authorization code for an ID token.
+func (p *oidcProvider) exchange(ctx context.Context, code, verifier string) (string, error) {
+	discovery, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.redirect},
+		"code_verifier": {verifier},
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.Token, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.SetBasicAuth(url.QueryEscape(p.client), url.QueryEscape(p.secret))
+
+	response, err := p.http.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	var payload struct {
+		IDToken string `json:"id_token"`
+		Error   string `json:"error"`
+	}
+
+	if err = json.NewDecoder(response.Body).Decode(&payload); err != nil {
+		return "", err
+	}
+
+	if response.StatusCode != http.StatusOK || payload.IDToken == "" {
+		return "", fmt.Errorf("token endpoint responded %d %s", response.StatusCode, payload.Error)
+	}
+
+	return payload.IDToken, nil
+}
+
+// verify checks the signature of the ID token against the keys of the
+// identity provider, along with its issuer, audience and lifetime.
+func (p *oidcProvider) verify(ctx context.Context, token string) (oidcClaims, error) {
+	var claims oidcClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(token *jwt.Token) (interface{}, error) {
+		if token.Method != jwt.SigningMethodRS256 {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+
+		kid, _ := token.Header["kid"].(string)
+		return p.key(ctx, kid)
+	})
+	if err != nil {
+		return oidcClaims{}, err
+	}
+
+	if strings.TrimSuffix(claims.Issuer, "/") != p.issuer {
+		return oidcClaims{}, errors.New("unexpected issuer")
+	}
+
+	for _, audience := range claims.Audience {
+		if audience == p.client {
+			return claims, nil
+		}
+	}
+
+	return oidcClaims{}, errors.New("unexpected audience")
+}
+
+// key returns the signing key with the key id, fetching the key set again
+// at most once a minute when it is unknown.
+func (p *oidcProvider) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	discovery, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+
+	if time.Since(p.fetched) < time.Minute {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+
+	var set struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+
+	if err = p.get(ctx, discovery.JWKS, &set); err != nil {
+		return nil, err
+	}
+
+	p.fetched = time.Now()
+	p.keys = map[string]*rsa.PublicKey{}
+	for _, item := range set.Keys {
+		if item.Kty != "RSA" || (item.Use != "" && item.Use != "sig") {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(item.N)
+		if err != nil {
+			continue
+		}
+
+		e, err := base64.RawURLEncoding.DecodeString(item.E)
+		if err != nil {
+			continue
+		}
+
+		p.keys[item.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("unknown key %q", kid)
+}
+
+func (p *oidcProvider) get(ctx context.Context, address string, target interface{}) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, address, nil)
+	if err != nil {
+		return err
+	}
+
+	response, err := p.http.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("identity provider responded %d to %s", response.StatusCode, address)
+	}
+
+	return json.NewDecoder(response.Body).Decode(target)
+}
@@ -0,0 +1,199 @@
+package management
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// testProvider is an identity provider issuing ID tokens for a fixed
+// identity, with the nonce of the last authorization request.
+type testProvider struct {
+	*httptest.Server
+	key   *rsa.PrivateKey
+	email string
+
+	mutex sync.Mutex
+	nonce string
+}
+
+func newTestProvider(t *testing.T, email string) *testProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := &testProvider{key: key, email: email}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(oidcDiscovery{
+			Issuer:        p.URL,
+			Authorization: p.URL + "/authorize",
+			Token:         p.URL + "/token",
+			JWKS:          p.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kid": "test",
+			"kty": "RSA",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		p.mutex.Lock()
+		nonce := p.nonce
+		p.mutex.Unlock()
+
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"iss":            p.URL,
+			"sub":            "subject",
+			"aud":            "client",
+			"exp":            time.Now().Add(time.Minute).Unix(),
+			"iat":            time.Now().Unix(),
+			"nonce":          nonce,
+			"email":          p.email,
+			"email_verified": true,
+		})
+		token.Header["kid"] = "test"
+
+		signed, err := token.SignedString(key)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]string{"id_token": signed})
+	})
+
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+	t.Setenv("OIDC_ISSUER", p.URL)
+	t.Setenv("OIDC_CLIENT", "client")
+	return p
+}
+
+// signIn runs single sign-on for the client and returns where the callback
+// redirected to.
+func (p *testProvider) signIn(t *testing.T, client *testClient) string {
+	t.Helper()
+	status, location := p.callback(t, client)
+	if status != http.StatusFound {
+		t.Fatalf("callback: got %d", status)
+	}
+
+	return location
+}
+
+// callback runs single sign-on for the client and returns the status and
+// location the callback answered with.
+func (p *testProvider) callback(t *testing.T, client *testClient) (int, string) {
+	t.Helper()
+	response, _ := client.send(httptest.NewRequest("GET", "/account/oidc/login", nil))
+	location, err := url.Parse(response.Header.Get("Location"))
+	if response.StatusCode != http.StatusFound || err != nil {
+		t.Fatalf("login: got %d %v", response.StatusCode, err)
+	}
+
+	p.mutex.Lock()
+	p.nonce = location.Query().Get("nonce")
+	p.mutex.Unlock()
+
+	query := url.Values{"code": {"code"}, "state": {location.Query().Get("state")}}
+	response, _ = client.send(httptest.NewRequest("GET", "/account/oidc/callback?"+query.Encode(), nil))
+	return response.StatusCode, response.Header.Get("Location")
+}
+
+func TestOIDCCallback(t *testing.T) {
+	p := newTestProvider(t, "owner@example.com")
+	s := newTestServer(t)
+	s.register(t, "owner@example.com", "Acme")
+	s.verify(t, "owner@example.com")
+
+	client := s.client(t)
+	if location := p.signIn(t, client); location != s.config.URL {
+		t.Fatalf("redirected to %s", location)
+	}
+
+	client.expect(http.StatusOK, "GET", "/account/authorize", "")
+
+	organization, err := s.storage.Organizations.FindByEmail(context.Background(), "owner@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if organization.Accounts[0].Subject != "subject" {
+		t.Fatalf("identity not linked %+v", organization.Accounts[0])
+	}
+}
+
+func TestOIDCCallbackUnverified(t *testing.T) {
+	p := newTestProvider(t, "victim@example.com")
+	s := newTestServer(t)
+
+	// registered by someone who cannot read the inbox of the address
+	attacker := s.register(t, "victim@example.com", "Attacker")
+
+	victim := s.client(t)
+	if status, _ := p.callback(t, victim); status != http.StatusForbidden {
+		t.Fatalf("got %d, want 403", status)
+	}
+
+	victim.expect(http.StatusUnauthorized, "GET", "/account/authorize", "")
+
+	var profile Member
+	decode(t, attacker.expect(http.StatusOK, "GET", "/account/profile", ""), &profile)
+	if profile.Verified {
+		t.Fatal("account verified by single sign-on")
+	}
+
+	organization, err := s.storage.Organizations.FindByEmail(context.Background(), "victim@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if organization.Accounts[0].Subject != "" {
+		t.Fatal("identity linked to an unverified account")
+	}
+}
+
+func TestOIDCCallbackTOTP(t *testing.T) {
+	p := newTestProvider(t, "owner@example.com")
+	s := newTestServer(t)
+	secret, _ := enroll(t, s.register(t, "owner@example.com", "Acme"))
+	s.verify(t, "owner@example.com")
+
+	client := s.client(t)
+	location := p.signIn(t, client)
+	client.expect(http.StatusUnauthorized, "GET", "/account/authorize", "")
+
+	prefix := s.config.URL + "/login/verify?token="
+	if !strings.HasPrefix(location, prefix) {
+		t.Fatalf("redirected to %s", location)
+	}
+
+	token, err := url.QueryUnescape(strings.TrimPrefix(location, prefix))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code := totp(secret, time.Now().Unix()/totpPeriod+1)
+	client.expect(http.StatusBadRequest, "POST", "/account/login/verify", `{"token":"`+token+`","code":"000000"}`)
+	client.expect(http.StatusOK, "POST", "/account/login/verify", `{"token":"`+token+`","code":"`+code+`"}`)
+	client.expect(http.StatusOK, "GET", "/account/authorize", "")
+}
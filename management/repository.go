@@ -0,0 +1,92 @@
+package management
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNotFound = errors.New("not found")
+	ErrConflict = errors.New("version conflict")
+)
+
+// Version identifies the revision of a stored document. A zero Version
+// means the document has never been saved.
+type Version struct {
+	SeqNo       int
+	PrimaryTerm int
+}
+
+func (v Version) IsZero() bool {
+	return v.PrimaryTerm == 0
+}
+
+// ETag renders the version as an HTTP entity tag.
+func (v Version) ETag() string {
+	return fmt.Sprintf(`"%d-%d"`, v.SeqNo, v.PrimaryTerm)
+}
+
+type OrganizationRepository interface {
+	FindByEmail(ctx context.Context, email string) (Organization, error)
+	FindByName(ctx context.Context, name string) (Organization, error)
+	FindByKey(ctx context.Context, key uuid.UUID) (Organization, error)
+	FindByAPIKey(ctx context.Context, key uuid.UUID) (Organization, error)
+	// Save stores the organization only if it is still at organization.Version,
+	// returning ErrConflict otherwise.
+	Save(ctx context.Context, organization Organization) (Version, error)
+}
+
+type CampaignRepository interface {
+	FindByKey(ctx context.Context, key uuid.UUID) (Campaign, error)
+	ListByOrganization(ctx context.Context, organization uuid.UUID) ([]Campaign, error)
+	Save(ctx context.Context, campaign Campaign) (Version, error)
+	// Remove deletes the campaign only if it is still at campaign.Version.
+	Remove(ctx context.Context, campaign Campaign) error
+}
+
+type ApplicationRepository interface {
+	FindByKey(ctx context.Context, key uuid.UUID) (Application, error)
+	FindByEmail(ctx context.Context, campaign uuid.UUID, email string) (Application, error)
+	ListByCampaign(ctx context.Context, campaign uuid.UUID) ([]Application, error)
+	Save(ctx context.Context, application Application) (Version, error)
+}
+
+type SessionRepository interface {
+	FindByKey(ctx context.Context, key uuid.UUID) (Session, error)
+	ListByFamily(ctx context.Context, family uuid.UUID) ([]Session, error)
+	ListByAccount(ctx context.Context, account uuid.UUID) ([]Session, error)
+	Save(ctx context.Context, session Session) (Version, error)
+}
+
+type RevocationRepository interface {
+	Revoke(ctx context.Context, revocation Revocation) error
+	IsRevoked(ctx context.Context, token uuid.UUID) (bool, error)
+	// Prune deletes the revocations of tokens expired before now, which
+	// Authenticate refuses anyway.
+	Prune(ctx context.Context, now time.Time) error
+}
+
+type InvitationRepository interface {
+	FindByKey(ctx context.Context, key uuid.UUID) (Invitation, error)
+	ListByOrganization(ctx context.Context, organization uuid.UUID) ([]Invitation, error)
+	Save(ctx context.Context, invitation Invitation) (Version, error)
+}
+
+type TicketRepository interface {
+	FindByKey(ctx context.Context, key uuid.UUID) (Ticket, error)
+	Save(ctx context.Context, ticket Ticket) (Version, error)
+}
+
+type Storage struct {
+	Organizations OrganizationRepository
+	Campaigns     CampaignRepository
+	Applications  ApplicationRepository
+	Sessions      SessionRepository
+	Revocations   RevocationRepository
+	Invitations   InvitationRepository
+	Tickets       TicketRepository
+}
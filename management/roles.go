@@ -0,0 +1,157 @@
+package management
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+var ranks = map[Role]int{RoleViewer: 1, RoleRecruiter: 2, RoleAdmin: 3, RoleOwner: 4}
+
+var errRole = fiber.NewError(http.StatusBadRequest, "role should be one of owner, admin, recruiter, viewer")
+
+// Includes reports whether the role grants everything other grants.
+func (r Role) Includes(other Role) bool {
+	return ranks[r] >= ranks[other]
+}
+
+// Permissions returns the role the account acts with. Accounts registered
+// before roles were introduced had full access and keep it as owners.
+func (a Account) Permissions() Role {
+	if a.Role == "" {
+		return RoleOwner
+	}
+
+	return a.Role
+}
+
+// Require rejects accounts whose role does not include role and API keys
+// holding none of scopes, so keys are refused where no scope is given. It must
+// be mounted after Authenticate or AuthenticateKey.
+func (s *server) Require(role Role, scopes ...Scope) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		principal := principal(c)
+		if principal.APIKey == nil {
+			if !principal.Account.Permissions().Includes(role) {
+				return fiber.NewError(http.StatusForbidden, "forbidden")
+			}
+
+			return c.Next()
+		}
+
+		for _, scope := range scopes {
+			if principal.APIKey.Allows(scope) {
+				return c.Next()
+			}
+		}
+
+		return fiber.NewError(http.StatusForbidden, "forbidden")
+	}
+}
+
+// @Summary ChangeRole
+// @Schemes
+// @Description Change the role of an organization member
+// @Tags account
+// @Accept application/json
+// @Param payload body RoleRequest true "body"
+// @Success 200 {object} string
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Router /account/role [patch]
+func (s *server) ChangeRole(c *fiber.Ctx) error {
+	var request RoleRequest
+	if err := json.Unmarshal(c.Body(), &request); err != nil {
+		return fiber.NewError(http.StatusBadRequest, err.Error())
+	}
+
+	if !request.Role.Valid() {
+		return errRole
+	}
+
+	_, err := s.modify(c, principal(c).Organization, func(organization *Organization) error {
+		index := member(*organization, request.Key)
+		if index == -1 {
+			return errNoAccount
+		}
+
+		organization.Accounts[index].Role = request.Role
+		if owners(*organization) == 0 {
+			return fiber.NewError(http.StatusBadRequest, "organization needs at least one owner")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.SendString("role changed")
+}
+
+// @Summary RemoveMember
+// @Schemes
+// @Description Remove an account from the organization
+// @Tags account
+// @Accept application/json
+// @Param key path string true "account key"
+// @Success 200 {object} string
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Router /account/remove/{key} [delete]
+func (s *server) RemoveMember(c *fiber.Ctx) error {
+	key, err := uuid.Parse(c.Params("key"))
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, "invalid account key")
+	}
+
+	_, err = s.modify(c, principal(c).Organization, func(organization *Organization) error {
+		index := member(*organization, key)
+		if index == -1 {
+			return errNoAccount
+		}
+
+		organization.Accounts = append(organization.Accounts[:index], organization.Accounts[index+1:]...)
+		if owners(*organization) == 0 {
+			return fiber.NewError(http.StatusBadRequest, "organization needs at least one owner")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = s.revokeAccount(c, key); err != nil {
+		return err
+	}
+
+	return c.SendString("member removed")
+}
+
+func member(organization Organization, key uuid.UUID) int {
+	for i, account := range organization.Accounts {
+		if account.Key == key {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func owners(organization Organization) int {
+	count := 0
+	for _, account := range organization.Accounts {
+		if account.Permissions() == RoleOwner {
+			count++
+		}
+	}
+
+	return count
+}
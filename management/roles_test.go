@@ -0,0 +1,168 @@
+package management
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// invite registers email into the organization of inviter with role.
+func (s *testServer) invite(t *testing.T, inviter *testClient, email string, role Role) *testClient {
+	t.Helper()
+	var invitation InvitationResponse
+	decode(t, inviter.expect(http.StatusOK, "POST", "/invitation/create", `{"email":"`+email+`","role":"`+string(role)+`"}`), &invitation)
+
+	client := s.client(t)
+	client.expect(http.StatusOK, "POST", "/account/register", `{"email":"`+email+`","password":"secret1","invitation":"`+invitation.Token+`"}`)
+	return client
+}
+
+// members lists the accounts of the organization by email.
+func members(t *testing.T, client *testClient) map[string]Member {
+	t.Helper()
+	var list []Member
+	decode(t, client.expect(http.StatusOK, "GET", "/account/members", ""), &list)
+
+	members := map[string]Member{}
+	for _, member := range list {
+		members[member.Email] = member
+	}
+
+	return members
+}
+
+func TestRolelessAccount(t *testing.T) {
+	s := newTestServer(t)
+	ctx := context.Background()
+	s.register(t, "owner@example.com", "Acme")
+	s.verify(t, "owner@example.com")
+
+	organization, err := s.storage.Organizations.FindByEmail(ctx, "owner@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// accounts registered before roles were introduced have none
+	organization.Accounts[0].Role = ""
+	if _, err = s.storage.Organizations.Save(ctx, organization); err != nil {
+		t.Fatal(err)
+	}
+
+	if organization, err = s.storage.Organizations.FindByKey(ctx, organization.Key); err != nil {
+		t.Fatal(err)
+	}
+
+	if organization.Accounts[0].Role != "" || organization.Accounts[0].Permissions() != RoleOwner {
+		t.Fatalf("unexpected account %+v", organization.Accounts[0])
+	}
+
+	// a failed login saves the organization again
+	client := s.client(t)
+	client.expect(http.StatusBadRequest, "POST", "/account/login", `{"email":"owner@example.com","password":"wrong1"}`)
+	client.expect(http.StatusOK, "POST", "/account/login", `{"email":"owner@example.com","password":"secret1"}`)
+
+	var identity Identity
+	decode(t, client.expect(http.StatusOK, "GET", "/account/authorize", ""), &identity)
+	if identity.Role != RoleOwner {
+		t.Fatalf("got role %s, want owner", identity.Role)
+	}
+
+	client.expect(http.StatusOK, "POST", "/apikey/create", `{"name":"ATS","scopes":["campaigns:read"]}`)
+}
+
+func TestRequire(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.register(t, "owner@example.com", "Acme")
+	s.verify(t, "owner@example.com")
+	clients := map[Role]*testClient{
+		RoleOwner:     owner,
+		RoleAdmin:     s.invite(t, owner, "admin@example.com", RoleAdmin),
+		RoleRecruiter: s.invite(t, owner, "recruiter@example.com", RoleRecruiter),
+		RoleViewer:    s.invite(t, owner, "viewer@example.com", RoleViewer),
+	}
+
+	owner.expect(http.StatusOK, "POST", "/campaign/create", `{"name":"Backend"}`)
+	var campaigns []Campaign
+	decode(t, owner.expect(http.StatusOK, "GET", "/campaigns", ""), &campaigns)
+	campaign := campaigns[0].Key.String()
+
+	routes := []struct {
+		method, path, body string
+		role               Role
+	}{
+		{"GET", "/campaigns", "", RoleViewer},
+		{"GET", "/account/members", "", RoleViewer},
+		{"GET", "/applications/" + campaign, "", RoleViewer},
+		{"POST", "/campaign/create", `{"name":"Frontend"}`, RoleRecruiter},
+		{"PATCH", "/campaign/update", `{"key":"` + campaign + `","wanted":2}`, RoleRecruiter},
+		{"GET", "/invitations", "", RoleAdmin},
+		{"GET", "/apikeys", "", RoleAdmin},
+		{"PATCH", "/account/role", `{"key":"` + uuid.NewString() + `","role":"viewer"}`, RoleOwner},
+		{"DELETE", "/account/remove/" + uuid.NewString(), "", RoleOwner},
+	}
+
+	for _, route := range routes {
+		for role, client := range clients {
+			code, data := client.do(route.method, route.path, route.body)
+			if allowed := role.Includes(route.role); allowed != (code != http.StatusForbidden) {
+				t.Errorf("%s %s as %s: got %d %s", route.method, route.path, role, code, data)
+			}
+		}
+	}
+}
+
+func TestChangeRole(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.register(t, "owner@example.com", "Acme")
+	admin := s.invite(t, owner, "admin@example.com", RoleAdmin)
+
+	list := members(t, owner)
+	ownerKey, adminKey := list["owner@example.com"].Key.String(), list["admin@example.com"].Key.String()
+
+	for _, body := range []string{
+		`{"key":"` + adminKey + `"}`,
+		`{"key":"` + adminKey + `","role":""}`,
+		`{"key":"` + adminKey + `","role":"root"}`,
+		`{"key":"` + ownerKey + `","role":"admin"}`,
+	} {
+		owner.expect(http.StatusBadRequest, "PATCH", "/account/role", body)
+	}
+
+	owner.expect(http.StatusNotFound, "PATCH", "/account/role", `{"key":"`+uuid.NewString()+`","role":"viewer"}`)
+
+	owner.expect(http.StatusOK, "PATCH", "/account/role", `{"key":"`+adminKey+`","role":"owner"}`)
+	owner.expect(http.StatusOK, "PATCH", "/account/role", `{"key":"`+ownerKey+`","role":"viewer"}`)
+
+	list = members(t, admin)
+	if list["owner@example.com"].Role != RoleViewer || list["admin@example.com"].Role != RoleOwner {
+		t.Fatalf("roles not changed %+v", list)
+	}
+
+	// the role is checked against the account, not the role in the token
+	owner.expect(http.StatusForbidden, "PATCH", "/account/role", `{"key":"`+ownerKey+`","role":"owner"}`)
+	admin.expect(http.StatusBadRequest, "PATCH", "/account/role", `{"key":"`+adminKey+`","role":"viewer"}`)
+}
+
+func TestRemoveMember(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.register(t, "owner@example.com", "Acme")
+	viewer := s.invite(t, owner, "viewer@example.com", RoleViewer)
+
+	list := members(t, owner)
+	ownerKey, viewerKey := list["owner@example.com"].Key.String(), list["viewer@example.com"].Key.String()
+
+	owner.expect(http.StatusBadRequest, "DELETE", "/account/remove/invalid", "")
+	owner.expect(http.StatusBadRequest, "DELETE", "/account/remove/"+ownerKey, "")
+	owner.expect(http.StatusNotFound, "DELETE", "/account/remove/"+uuid.NewString(), "")
+	viewer.expect(http.StatusForbidden, "DELETE", "/account/remove/"+ownerKey, "")
+
+	owner.expect(http.StatusOK, "DELETE", "/account/remove/"+viewerKey, "")
+	viewer.expect(http.StatusUnauthorized, "GET", "/account/authorize", "")
+	viewer.expect(http.StatusUnauthorized, "POST", "/account/refresh", "")
+
+	if _, ok := members(t, owner)["viewer@example.com"]; ok {
+		t.Fatal("member not removed")
+	}
+}
@@ -0,0 +1,144 @@
+package management
+
+import (
+	"errors"
+	"strings"
+)
+
+type Score struct {
+	Value float32 `json:"value"`
+	// Disqualified is set when the candidate misses a mandatory criterion.
+	Disqualified bool `json:"disqualified"`
+	// Unweighted is set when the campaign has requirements but none of them
+	// carries weight, so the score cannot tell candidates apart.
+	Unweighted bool             `json:"unweighted"`
+	Criteria   []CriterionScore `json:"criteria"`
+}
+
+type CriterionScore struct {
+	Name    string   `json:"name"`
+	Weight  float32  `json:"weight"`
+	Value   float32  `json:"value"`
+	Matched []string `json:"matched"`
+	Missing []string `json:"missing"`
+}
+
+// Evaluate compares an application with the requirements of a campaign. Each
+// criterion the campaign specifies is scored as the weighted fraction of
+// required items the candidate has, and the overall value is the mean of those
+// fractions weighted by the campaign Weights, so it always lies between 0 and
+// 1. A campaign without requirements scores 1, one whose requirements carry no
+// weight scores 0 and is marked Unweighted.
+func Evaluate(campaign Campaign, application Application) Score {
+	weights := DefaultWeights
+	if campaign.Weights != nil {
+		weights = *campaign.Weights
+	}
+
+	criteria := []struct {
+		name      string
+		weight    float32
+		required  []Criterion
+		candidate []string
+	}{
+		{"education", weights.Education, campaign.Education, application.Education},
+		{"experience", weights.Experience, campaign.Experience, application.Experience},
+		{"certificates", weights.Certificates, campaign.Certificates, application.Certificates},
+		{"courses", weights.Courses, campaign.Courses, application.Courses},
+		{"skills", weights.Skills, campaign.Skills, application.Skills},
+		{"languages", weights.Languages, campaign.Languages, application.Languages},
+	}
+
+	score := Score{Value: 1, Criteria: []CriterionScore{}}
+	var total, sum float32
+	for _, criterion := range criteria {
+		if len(criterion.required) == 0 {
+			continue
+		}
+
+		result := CriterionScore{Name: criterion.name, Weight: criterion.weight, Matched: []string{}, Missing: []string{}}
+		var matched, required float32
+		for _, item := range criterion.required {
+			required += item.Weight
+			if contains(criterion.candidate, item.Name) {
+				matched += item.Weight
+				result.Matched = append(result.Matched, item.Name)
+				continue
+			}
+
+			result.Missing = append(result.Missing, item.Name)
+			if item.Mandatory {
+				score.Disqualified = true
+			}
+		}
+
+		if required > 0 {
+			result.Value = matched / required
+			total += criterion.weight * result.Value
+			sum += criterion.weight
+		}
+
+		score.Criteria = append(score.Criteria, result)
+	}
+
+	switch {
+	case sum > 0:
+		score.Value = total / sum
+	case len(score.Criteria) > 0:
+		score.Value = 0
+		score.Unweighted = true
+	}
+
+	return score
+}
+
+// Decide maps a score onto the campaign thresholds. Disqualified scores and
+// scores below Reject are rejected and scores reaching Accept are accepted; a
+// zero threshold disables the corresponding automatic decision. Unweighted
+// scores are always left for review.
+func (s Score) Decide(campaign Campaign) Status {
+	switch {
+	case s.Disqualified:
+		return StatusRejected
+	case s.Unweighted:
+		return StatusReview
+	case s.Value < campaign.Reject:
+		return StatusRejected
+	case campaign.Accept > 0 && s.Value >= campaign.Accept:
+		return StatusAccepted
+	default:
+		return StatusReview
+	}
+}
+
+// validateCampaign ensures the thresholds and weights fit Evaluate scores.
+func validateCampaign(campaign Campaign) error {
+	if campaign.Accept < 0 || campaign.Accept > 1 || campaign.Reject < 0 || campaign.Reject > 1 {
+		return errors.New("accept and reject should be between 0 and 1")
+	}
+
+	if campaign.Accept > 0 && campaign.Reject > campaign.Accept {
+		return errors.New("reject should not be greater than accept")
+	}
+
+	if w := campaign.Weights; w != nil && w.Education+w.Experience+w.Certificates+w.Courses+w.Skills+w.Languages == 0 {
+		return errors.New("at least one weight should be positive")
+	}
+
+	if Evaluate(campaign, Application{}).Unweighted {
+		return errors.New("requirements should be in a category with a positive weight")
+	}
+
+	return nil
+}
+
+func contains(items []string, value string) bool {
+	value = strings.TrimSpace(value)
+	for _, item := range items {
+		if strings.EqualFold(strings.TrimSpace(item), value) {
+			return true
+		}
+	}
+
+	return false
+}
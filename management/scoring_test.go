@@ -0,0 +1,217 @@
+package management
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func criteria(names ...string) []Criterion {
+	items := make([]Criterion, 0, len(names))
+	for _, name := range names {
+		items = append(items, Criterion{Name: name, Weight: 1})
+	}
+
+	return items
+}
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name         string
+		campaign     Campaign
+		application  Application
+		value        float32
+		disqualified bool
+		unweighted   bool
+	}{
+		{
+			name:        "no requirements",
+			application: Application{Skills: []string{"Go"}},
+			value:       1,
+		},
+		{
+			name:        "all matched",
+			campaign:    Campaign{Skills: criteria("Go", "SQL")},
+			application: Application{Skills: []string{"SQL", "Go"}},
+			value:       1,
+		},
+		{
+			name:        "nothing matched",
+			campaign:    Campaign{Skills: criteria("Go", "SQL")},
+			application: Application{Skills: []string{"Java"}},
+			value:       0,
+		},
+		{
+			name:        "half matched",
+			campaign:    Campaign{Skills: criteria("Go", "SQL")},
+			application: Application{Skills: []string{"Go"}},
+			value:       0.5,
+		},
+		{
+			name:        "case and whitespace",
+			campaign:    Campaign{Skills: criteria(" Go ", "PostgreSQL")},
+			application: Application{Skills: []string{"go", "  postgresql"}},
+			value:       1,
+		},
+		{
+			name:        "weighted items",
+			campaign:    Campaign{Skills: []Criterion{{Name: "Go", Weight: 3}, {Name: "SQL", Weight: 1}}},
+			application: Application{Skills: []string{"SQL"}},
+			value:       0.25,
+		},
+		{
+			name:        "missing weights count categories equally",
+			campaign:    Campaign{Skills: criteria("Go"), Languages: criteria("English")},
+			application: Application{Skills: []string{"Go"}},
+			value:       0.5,
+		},
+		{
+			name: "category weights",
+			campaign: Campaign{
+				Weights:   &Weights{Skills: 3, Languages: 1},
+				Skills:    criteria("Go"),
+				Languages: criteria("English"),
+			},
+			application: Application{Skills: []string{"Go"}},
+			value:       0.75,
+		},
+		{
+			name: "zero weight category is ignored",
+			campaign: Campaign{
+				Weights:   &Weights{Skills: 0, Languages: 1},
+				Skills:    criteria("Go"),
+				Languages: criteria("English"),
+			},
+			application: Application{Languages: []string{"English"}},
+			value:       1,
+		},
+		{
+			name: "only zero weight categories required",
+			campaign: Campaign{
+				Weights: &Weights{Skills: 0, Languages: 1},
+				Skills:  criteria("Go"),
+			},
+			application: Application{Skills: []string{"Java"}},
+			value:       0,
+			unweighted:  true,
+		},
+		{
+			name:        "only zero weight items",
+			campaign:    Campaign{Skills: []Criterion{{Name: "Go"}}},
+			application: Application{Skills: []string{"Go"}},
+			value:       0,
+			unweighted:  true,
+		},
+		{
+			name:         "mandatory item missing",
+			campaign:     Campaign{Skills: []Criterion{{Name: "Go", Weight: 1, Mandatory: true}, {Name: "SQL", Weight: 1}}},
+			application:  Application{Skills: []string{"SQL"}},
+			value:        0.5,
+			disqualified: true,
+		},
+		{
+			name:        "mandatory item matched",
+			campaign:    Campaign{Skills: []Criterion{{Name: "Go", Weight: 1, Mandatory: true}, {Name: "SQL", Weight: 1}}},
+			application: Application{Skills: []string{"Go"}},
+			value:       0.5,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			score := Evaluate(test.campaign, test.application)
+			if score.Value != test.value || score.Disqualified != test.disqualified || score.Unweighted != test.unweighted {
+				t.Fatalf("got %+v, want value %v disqualified %v unweighted %v", score, test.value, test.disqualified, test.unweighted)
+			}
+		})
+	}
+}
+
+func TestEvaluateCriteria(t *testing.T) {
+	campaign := Campaign{Skills: criteria("Go", "SQL")}
+	score := Evaluate(campaign, Application{Skills: []string{"go"}})
+
+	if len(score.Criteria) != 1 {
+		t.Fatalf("got %d criteria, want 1", len(score.Criteria))
+	}
+
+	result := score.Criteria[0]
+	if result.Name != "skills" || result.Value != 0.5 || len(result.Matched) != 1 || result.Matched[0] != "Go" || len(result.Missing) != 1 || result.Missing[0] != "SQL" {
+		t.Fatalf("unexpected criterion score %+v", result)
+	}
+}
+
+func TestEvaluateLegacyCriteria(t *testing.T) {
+	var campaign Campaign
+	decode(t, `{"skills":["Go","SQL"],"languages":[{"name":"English","weight":2,"mandatory":true}]}`, &campaign)
+
+	if campaign.Skills[0] != (Criterion{Name: "Go", Weight: 1}) || campaign.Languages[0] != (Criterion{Name: "English", Weight: 2, Mandatory: true}) {
+		t.Fatalf("unexpected criteria %+v %+v", campaign.Skills, campaign.Languages)
+	}
+
+	score := Evaluate(campaign, Application{Skills: []string{"Go"}, Languages: []string{"English"}})
+	if score.Value != 0.75 || score.Disqualified {
+		t.Fatalf("got %+v", score)
+	}
+}
+
+func TestCriterionUnmarshal(t *testing.T) {
+	for _, data := range []string{`{"weight":1}`, `{"name":" "}`, `{"name":"Go","weight":-1}`, `{"name":"Go","weight":0}`, `1`} {
+		var criterion Criterion
+		if err := json.Unmarshal([]byte(data), &criterion); err == nil {
+			t.Errorf("%s: accepted as %+v", data, criterion)
+		}
+	}
+}
+
+func TestDecide(t *testing.T) {
+	tests := []struct {
+		name     string
+		score    Score
+		campaign Campaign
+		status   Status
+	}{
+		{"above accept", Score{Value: 0.9}, Campaign{Accept: 0.8, Reject: 0.3}, StatusAccepted},
+		{"at accept", Score{Value: 0.8}, Campaign{Accept: 0.8, Reject: 0.3}, StatusAccepted},
+		{"between thresholds", Score{Value: 0.5}, Campaign{Accept: 0.8, Reject: 0.3}, StatusReview},
+		{"at reject", Score{Value: 0.3}, Campaign{Accept: 0.8, Reject: 0.3}, StatusReview},
+		{"below reject", Score{Value: 0.2}, Campaign{Accept: 0.8, Reject: 0.3}, StatusRejected},
+		{"disqualified", Score{Value: 1, Disqualified: true}, Campaign{Accept: 0.8}, StatusRejected},
+		{"zero accept disables acceptance", Score{Value: 1}, Campaign{}, StatusReview},
+		{"zero reject disables rejection", Score{Value: 0}, Campaign{Accept: 0.8}, StatusReview},
+		{"unweighted", Score{Value: 0, Unweighted: true}, Campaign{Accept: 0.8, Reject: 0.3}, StatusReview},
+		{"unweighted and disqualified", Score{Value: 0, Unweighted: true, Disqualified: true}, Campaign{Accept: 0.8}, StatusRejected},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if status := test.score.Decide(test.campaign); status != test.status {
+				t.Fatalf("got %s, want %s", status, test.status)
+			}
+		})
+	}
+}
+
+func TestValidateCampaign(t *testing.T) {
+	tests := []struct {
+		name     string
+		campaign Campaign
+		valid    bool
+	}{
+		{"empty", Campaign{}, true},
+		{"thresholds", Campaign{Accept: 0.8, Reject: 0.3}, true},
+		{"accept above 1", Campaign{Accept: 1.5}, false},
+		{"negative reject", Campaign{Reject: -0.1}, false},
+		{"reject above accept", Campaign{Accept: 0.3, Reject: 0.8}, false},
+		{"zero weights", Campaign{Weights: &Weights{}}, false},
+		{"requirements in a zero weight category", Campaign{Weights: &Weights{Languages: 1}, Skills: criteria("Go")}, false},
+		{"requirements in a weighted category", Campaign{Weights: &Weights{Skills: 1}, Skills: criteria("Go")}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := validateCampaign(test.campaign); (err == nil) != test.valid {
+				t.Fatalf("got %v, want valid %v", err, test.valid)
+			}
+		})
+	}
+}
@@ -1,52 +1,108 @@
 package management
 
 import (
-	"bytes"
-	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
-	"github.com/elastic/go-elasticsearch/v8"
-	"github.com/elastic/go-elasticsearch/v8/esapi"
 	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt"
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type server struct {
-	storage       *elasticsearch.Client
+	organizations OrganizationRepository
+	campaigns     CampaignRepository
+	applications  ApplicationRepository
+	sessions      SessionRepository
+	revocations   RevocationRepository
+	invitations   InvitationRepository
+	tickets       TicketRepository
+	mailer        Mailer
+	attempts      *throttle
+	oidc          *oidcProvider
+	keyring       *Keyring
 	configuration Config
 }
 
-func NewServer(s *elasticsearch.Client, c Config) *server {
+func NewServer(s Storage, m Mailer, k *Keyring, c Config) *server {
 	return &server{
-		storage:       s,
+		organizations: s.Organizations,
+		campaigns:     s.Campaigns,
+		applications:  s.Applications,
+		sessions:      s.Sessions,
+		revocations:   s.Revocations,
+		invitations:   s.Invitations,
+		tickets:       s.Tickets,
+		mailer:        m,
+		attempts:      newThrottle(time.Duration(c.LoginBackoff)*time.Second, time.Duration(c.LoginLockout)*time.Minute),
+		oidc:          newOIDCProvider(c, &http.Client{Timeout: 10 * time.Second}),
+		keyring:       k,
 		configuration: c,
 	}
 }
 
 func (s *server) Chain(r fiber.Router) {
+	r.Use(s.CSRF)
+
 	// authentication
 	r.Post("/account/register", s.Register)
 	r.Post("/account/login", s.Login)
-	r.Get("/account/logout", s.Logout)
-	r.Get("/account/authorize", s.Authorize)
+	r.Post("/account/login/verify", s.VerifyLogin)
+	r.Get("/account/oidc/login", s.OIDCLogin)
+	r.Get("/account/oidc/callback", s.OIDCCallback)
+	r.Post("/account/refresh", s.Refresh)
+	r.Post("/account/logout", s.Logout)
+	r.Post("/account/forgot", s.Forgot)
+	r.Post("/account/reset", s.Reset)
+	r.Get("/account/verify", s.Verify)
+	r.Post("/account/verify/resend", s.Authenticate, s.ResendVerification)
+	r.Post("/account/revoke", s.Authenticate, s.RevokeSessions)
+	r.Get("/account/authorize", s.Authenticate, s.Authorize)
+	r.Get("/account/profile", s.Authenticate, s.Profile)
+	r.Patch("/account/password", s.Authenticate, s.ChangePassword)
+	r.Patch("/account/email", s.Authenticate, s.ChangeEmail)
+	r.Get("/account/email/confirm", s.ConfirmEmail)
+	r.Post("/account/totp/enroll", s.Authenticate, s.EnrollTOTP)
+	r.Post("/account/totp/confirm", s.Authenticate, s.ConfirmTOTP)
+	r.Post("/account/totp/recovery", s.Authenticate, s.RegenerateRecovery)
+	r.Post("/account/totp/disable", s.Authenticate, s.DisableTOTP)
+
+	// members
+	r.Get("/account/members", s.Authenticate, s.Require(RoleViewer), s.ListMembers)
+	r.Patch("/account/role", s.Authenticate, s.Require(RoleOwner), s.ChangeRole)
+	r.Delete("/account/remove/:key", s.Authenticate, s.Require(RoleOwner), s.RemoveMember)
+
+	// invitations
+	r.Post("/invitation/create", s.Authenticate, s.Require(RoleAdmin), s.CreateInvitation)
+	r.Get("/invitations", s.Authenticate, s.Require(RoleAdmin), s.ListInvitations)
+
+	// api keys
+	r.Post("/apikey/create", s.Authenticate, s.Require(RoleAdmin), s.CreateAPIKey)
+	r.Get("/apikeys", s.Authenticate, s.Require(RoleAdmin), s.ListAPIKeys)
+	r.Delete("/apikey/revoke/:key", s.Authenticate, s.Require(RoleAdmin), s.RevokeAPIKey)
 
 	// campaigns
-	r.Get("/campaigns", s.ListCampaigns)
-	r.Post("/campaign/create", s.CreateCampaign)
-	r.Patch("/campaign/update", s.UpdateCampaign)
-	r.Delete("/campaign/remove/:key", s.RemoveCampaign)
+	r.Get("/campaigns", s.AuthenticateKey, s.Require(RoleViewer, ScopeCampaignsRead), s.ListCampaigns)
+	r.Get("/campaign/:key", s.AuthenticateKey, s.Require(RoleViewer, ScopeCampaignsRead), s.GetCampaign)
+	r.Post("/campaign/create", s.AuthenticateKey, s.Require(RoleRecruiter, ScopeCampaignsWrite), s.CreateCampaign)
+	r.Patch("/campaign/update", s.AuthenticateKey, s.Require(RoleRecruiter, ScopeCampaignsWrite), s.UpdateCampaign)
+	r.Delete("/campaign/remove/:key", s.AuthenticateKey, s.Require(RoleAdmin, ScopeCampaignsWrite), s.RemoveCampaign)
+
+	// applications
+	r.Post("/application/submit/:campaign", s.SubmitApplication)
+	r.Get("/applications/:campaign", s.AuthenticateKey, s.Require(RoleViewer, ScopeApplicationsRead), s.ListApplications)
+	r.Get("/application/:key", s.AuthenticateKey, s.Require(RoleViewer, ScopeApplicationsRead), s.GetApplication)
+	r.Patch("/application/status", s.AuthenticateKey, s.Require(RoleRecruiter, ScopeApplicationsWrite), s.UpdateApplicationStatus)
 }
 
 // @Summary Register
 // @Schemes
-// @Description Register new user
+// @Description Register new user with a new company, or into an existing one with an invitation
 // @Tags account
 // @Accept application/json
 // @Param payload body RegisterRequest true "body"
@@ -61,39 +117,14 @@ func (s *server) Register(c *fiber.Ctx) error {
 		return fiber.NewError(http.StatusBadRequest, err.Error())
 	}
 
-	query := strings.NewReader(fmt.Sprintf(`{ "query": { "match_phrase": { "accounts.email": "%s" } } }`, request.Email))
-	response, err := s.storage.Search(s.storage.Search.WithIndex(s.configuration.Index), s.storage.Search.WithBody(query))
-	if err != nil {
-		return fiber.NewError(http.StatusServiceUnavailable, err.Error())
-	}
-	defer response.Body.Close()
-
-	var payload struct {
-		Hits struct {
-			Hits []struct {
-				Source Organization `json:"_source"`
-			} `json:"hits"`
-		} `json:"hits"`
-	}
-
-	if err = json.NewDecoder(response.Body).Decode(&payload); err != nil {
-		return err
-	}
-
-	if len(payload.Hits.Hits) > 0 {
+	_, err := s.organizations.FindByEmail(c.UserContext(), request.Email.String())
+	if err == nil {
 		return fiber.NewError(http.StatusBadRequest, "user with this email already exists")
 	}
 
-	query = strings.NewReader(fmt.Sprintf(`{ "query": { "match_phrase": { "name": "%s" } } }`, request.Company))
-	response, err = s.storage.Search(s.storage.Search.WithIndex(s.configuration.Index), s.storage.Search.WithBody(query))
-	if err != nil {
+	if !errors.Is(err, ErrNotFound) {
 		return fiber.NewError(http.StatusServiceUnavailable, err.Error())
 	}
-	defer response.Body.Close()
-
-	if err = json.NewDecoder(response.Body).Decode(&payload); err != nil {
-		return err
-	}
 
 	password, err := bcrypt.GenerateFromPassword([]byte(request.Password), 14)
 	if err != nil {
@@ -104,64 +135,78 @@ func (s *server) Register(c *fiber.Ctx) error {
 		Key:      uuid.New(),
 		Email:    request.Email.String(),
 		Password: password,
+		Role:     RoleOwner,
 		Created:  time.Now(),
 	}
 
-	organization := Organization{
-		Key:       uuid.New(),
-		Name:      string(request.Company),
-		Accounts:  []Account{account},
-		Campaigns: []Campaign{},
-		Created:   time.Now(),
-	}
+	var organization Organization
+	if request.Invitation != "" {
+		invitation, err := s.redeem(c, request.Invitation, request.Email.String())
+		if err != nil {
+			return err
+		}
 
-	if len(payload.Hits.Hits) > 0 {
-		organization = payload.Hits.Hits[0].Source
-		organization.Accounts = append(organization.Accounts, account)
-	}
+		if organization, err = s.organizations.FindByKey(c.UserContext(), invitation.Organization); err != nil {
+			return storageError(err)
+		}
 
-	data, err := json.Marshal(organization)
-	if err != nil {
-		return err
-	}
+		// the token of the invitation is also handed to the inviting admin, so
+		// it does not prove the address
+		account.Role = invitation.Role
+		organization, err = s.modify(c, organization, func(organization *Organization) error {
+			organization.Accounts = append(organization.Accounts, account)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	} else {
+		if request.Company == "" {
+			return fiber.NewError(http.StatusBadRequest, "company is required")
+		}
 
-	create := esapi.IndexRequest{Index: s.configuration.Index, DocumentID: organization.Key.String(), Body: bytes.NewReader(data)}
-	response, err = create.Do(context.Background(), s.storage)
-	if err != nil {
-		return fiber.NewError(http.StatusServiceUnavailable, err.Error())
-	}
-	defer response.Body.Close()
+		_, err = s.organizations.FindByName(c.UserContext(), string(request.Company))
+		if err == nil {
+			return fiber.NewError(http.StatusBadRequest, "company already exists, ask its members for an invitation")
+		}
+
+		if !errors.Is(err, ErrNotFound) {
+			return fiber.NewError(http.StatusServiceUnavailable, err.Error())
+		}
 
-	claims := jwt.MapClaims{
-		"Issuer":    organization.Key,
-		"Company":   organization.Name,
-		"ExpiresAt": time.Now().Add(time.Hour).Unix(),
+		organization = Organization{
+			Key:      uuid.New(),
+			Name:     string(request.Company),
+			Accounts: []Account{account},
+			Created:  time.Now(),
+		}
+
+		if _, err := s.organizations.Save(c.UserContext(), organization); err != nil {
+			return storageError(err)
+		}
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	value, err := token.SignedString([]byte(s.configuration.Secret))
-	if err != nil {
+
+	if err := s.verification(c, organization, account); err != nil {
 		return err
 	}
 
-	c.Cookie(&fiber.Cookie{
-		Name: s.configuration.Cookie, Value: value,
-		Secure:   true,
-		HTTPOnly: false,
-		SameSite: "none",
-		Expires:  time.Now().Add(time.Duration(s.configuration.Expiration) * time.Hour),
-	})
+	if err := s.login(c, organization, account); err != nil {
+		return err
+	}
 
 	return c.SendString("user registered")
 }
 
 // @Summary Login
 // @Schemes
-// @Description Login existing user
+// @Description Login existing user, answering with a challenge when the account has a second factor
 // @Tags account
 // @Accept application/json
 // @Param payload body Request true "body"
 // @Success 200 {object} string
+// @Success 202 {object} Challenge
 // @Success 400
+// @Failure 429
 // @Failure 500
 // @Failure 503
 // @Router /account/login [post]
@@ -171,71 +216,107 @@ func (s *server) Login(c *fiber.Ctx) error {
 		return fiber.NewError(http.StatusBadRequest, err.Error())
 	}
 
-	query := strings.NewReader(fmt.Sprintf(`{ "query": { "match_phrase": { "accounts.email": "%s" } } }`, request.Email))
-	response, err := s.storage.Search(s.storage.Search.WithIndex(s.configuration.Index), s.storage.Search.WithBody(query))
+	email, address := "email:"+strings.ToLower(request.Email.String()), "ip:"+c.IP()
+	for _, key := range []string{email, address} {
+		if wait := s.attempts.Wait(key, time.Now()); wait > 0 {
+			return tooManyAttempts(c, wait)
+		}
+	}
+
+	failed := func(message string) error {
+		s.attempts.Fail(email, 0, time.Now())
+		s.attempts.Fail(address, s.configuration.LoginIPAttempts, time.Now())
+		return fiber.NewError(http.StatusBadRequest, message)
+	}
+
+	organization, err := s.organizations.FindByEmail(c.UserContext(), request.Email.String())
+	if errors.Is(err, ErrNotFound) {
+		return failed("no user with this email address")
+	}
+
 	if err != nil {
-		return err
+		return fiber.NewError(http.StatusServiceUnavailable, err.Error())
 	}
-	defer response.Body.Close()
 
-	var payload struct {
-		Hits struct {
-			Hits []struct {
-				Source Organization `json:"_source"`
-			} `json:"hits"`
-		} `json:"hits"`
+	account, ok := organization.AccountByEmail(request.Email.String())
+	if !ok {
+		return failed("no user with this email address")
 	}
 
-	if err = json.NewDecoder(response.Body).Decode(&payload); err != nil {
-		return err
+	if wait := time.Until(account.Locked); wait > 0 {
+		return tooManyAttempts(c, wait)
 	}
 
-	if len(payload.Hits.Hits) == 0 {
-		return fiber.NewError(http.StatusBadRequest, "no user with this email address")
+	if err := bcrypt.CompareHashAndPassword(account.Password, []byte(request.Password)); err != nil {
+		if err := s.failure(c, organization, account.Key); err != nil {
+			return err
+		}
+
+		return failed("incorrect password")
 	}
 
-	if err := bcrypt.CompareHashAndPassword(payload.Hits.Hits[0].Source.Accounts[0].Password, []byte(request.Password)); err != nil {
-		return fiber.NewError(http.StatusBadRequest, "incorrect password")
+	s.attempts.Reset(email)
+	if account.TOTP.Enabled() {
+		return s.challenge(c, organization, account)
 	}
 
-	claims := jwt.MapClaims{
-		"Issuer":    payload.Hits.Hits[0].Source.Key,
-		"Company":   payload.Hits.Hits[0].Source.Name,
-		"ExpiresAt": time.Now().Add(time.Hour).Unix(),
+	if err := s.success(c, organization, account.Key); err != nil {
+		return err
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	value, err := token.SignedString([]byte(s.configuration.Secret))
-	if err != nil {
+
+	if err := s.login(c, organization, account); err != nil {
 		return err
 	}
 
-	c.Cookie(&fiber.Cookie{
-		Name: s.configuration.Cookie, Value: value,
-		Secure:   true,
-		HTTPOnly: false,
-		SameSite: "none",
-		Expires:  time.Now().Add(time.Duration(s.configuration.Expiration) * time.Hour),
+	return c.SendString("user logged")
+}
+
+// failure counts a failed login of the account and locks it once
+// Config.LoginAttempts is reached.
+func (s *server) failure(c *fiber.Ctx, organization Organization, key uuid.UUID) error {
+	_, err := s.modifyAccount(c, organization, key, func(account *Account) error {
+		account.Failures++
+		if account.Failures >= s.configuration.LoginAttempts {
+			account.Failures = 0
+			account.Locked = time.Now().Add(time.Duration(s.configuration.LoginLockout) * time.Minute)
+		}
+
+		return nil
 	})
 
-	return c.SendString("user logged")
+	return err
+}
+
+// success clears the failed logins of the account.
+func (s *server) success(c *fiber.Ctx, organization Organization, key uuid.UUID) error {
+	if account, _ := organization.AccountByKey(key); account.Failures == 0 {
+		return nil
+	}
+
+	_, err := s.modifyAccount(c, organization, key, func(account *Account) error {
+		account.Failures = 0
+		return nil
+	})
+
+	return err
 }
 
 // @Summary Logout
 // @Schemes
-// @Description Logout existing user
+// @Description Logout existing user and revoke the session
 // @Tags account
 // @Accept application/json
 // @Success 200 {object} string
-// @Router /account/logout [get]
+// @Failure 403
+// @Router /account/logout [post]
 func (s *server) Logout(c *fiber.Ctx) error {
-	c.Cookie(&fiber.Cookie{
-		Name:     s.configuration.Cookie,
-		Value:    "",
-		Secure:   true,
-		HTTPOnly: false,
-		SameSite: "none",
-		Expires:  time.Now().Add(-time.Second),
-	})
+	if session, err := s.session(c); err == nil {
+		if err = s.revoke(c, session.Family); err != nil {
+			return err
+		}
+	}
+
+	s.clearCookies(c)
 	return c.SendString("user logout")
 }
 
@@ -244,23 +325,19 @@ func (s *server) Logout(c *fiber.Ctx) error {
 // @Description Authorize existing user
 // @Tags account
 // @Accept application/json
-// @Success 200 {object} string
+// @Success 200 {object} Identity
 // @Success 401 {object} string
 // @Router /account/authorize [get]
 func (s *server) Authorize(c *fiber.Ctx) error {
-	cookie := c.Cookies(s.configuration.Cookie)
-	parser := func(token *jwt.Token) (interface{}, error) { return []byte(s.configuration.Secret), nil }
-
-	token, err := jwt.Parse(cookie, parser)
-	if err != nil {
-		return fiber.NewError(http.StatusUnauthorized, "unauthorized")
-	}
-
-	payload := token.Claims.(jwt.MapClaims)
-
-	id := payload["Issuer"].(string)
-
-	return c.SendString("user with id: " + id)
+	principal := principal(c)
+
+	return c.JSON(Identity{
+		Organization: principal.Organization.Key,
+		Company:      principal.Organization.Name,
+		Account:      principal.Account.Key,
+		Email:        principal.Account.Email,
+		Role:         principal.Account.Permissions(),
+	})
 }
 
 // Campaigns
@@ -271,48 +348,35 @@ func (s *server) Authorize(c *fiber.Ctx) error {
 // @Description ListCampaigns
 // @Tags campaigns
 // @Accept application/json
-// @Success 200 {object} string
+// @Success 200 {array} Campaign
 // @Router /campaigns [get]
 func (s *server) ListCampaigns(c *fiber.Ctx) error {
-	// TODO: refactor later
-	cookie := c.Cookies(s.configuration.Cookie)
-	parser := func(token *jwt.Token) (interface{}, error) { return []byte(s.configuration.Secret), nil }
-
-	token, err := jwt.Parse(cookie, parser)
-	if err != nil {
-		return fiber.NewError(http.StatusUnauthorized, "unauthorized")
-	}
-
-	claims := token.Claims.(jwt.MapClaims)
-	company := claims["Company"].(string)
-
-	query := strings.NewReader(fmt.Sprintf(`{ "_source": { "include": [ "campaigns" ] }, "query": { "match_phrase": { "name": "%s" } } }`, company))
-	response, err := s.storage.Search(s.storage.Search.WithIndex(s.configuration.Index), s.storage.Search.WithBody(query))
+	campaigns, err := s.campaigns.ListByOrganization(c.UserContext(), principal(c).Organization.Key)
 	if err != nil {
-		return err
+		return storageError(err)
 	}
-	defer response.Body.Close()
 
-	var payload struct {
-		Hits struct {
-			Hits []struct {
-				Source struct {
-					Campaigns []Campaign `json:"campaigns"`
-				} `json:"_source"`
-			} `json:"hits"`
-		} `json:"hits"`
-	}
-
-	if err = json.NewDecoder(response.Body).Decode(&payload); err != nil {
-		return err
-	}
+	return c.JSON(campaigns)
+}
 
-	output, err := json.Marshal(payload.Hits.Hits[0].Source.Campaigns)
+// @Summary GetCampaign
+// @Schemes
+// @Description Get a single campaign, its version is returned as ETag
+// @Tags campaigns
+// @Accept application/json
+// @Param key path string true "campaign key"
+// @Success 200 {object} Campaign
+// @Failure 400
+// @Failure 404
+// @Router /campaign/{key} [get]
+func (s *server) GetCampaign(c *fiber.Ctx) error {
+	campaign, err := s.campaign(c, c.Params("key"))
 	if err != nil {
 		return err
 	}
 
-	return c.Send(output)
+	c.Set(fiber.HeaderETag, campaign.Version.ETag())
+	return c.JSON(campaign)
 }
 
 // @Summary CreateCampaign
@@ -329,40 +393,9 @@ func (s *server) CreateCampaign(c *fiber.Ctx) error {
 		return err
 	}
 
-	// TODO: refactor later
-	cookie := c.Cookies(s.configuration.Cookie)
-	parser := func(token *jwt.Token) (interface{}, error) { return []byte(s.configuration.Secret), nil }
-
-	token, err := jwt.Parse(cookie, parser)
-	if err != nil {
-		return fiber.NewError(http.StatusUnauthorized, "unauthorized")
-	}
-
-	claims := token.Claims.(jwt.MapClaims)
-	company := claims["Company"].(string)
-
-	query := strings.NewReader(fmt.Sprintf(`{ "query": { "match_phrase": { "name": "%s" } } }`, company))
-	response, err := s.storage.Search(s.storage.Search.WithIndex(s.configuration.Index), s.storage.Search.WithBody(query))
-	if err != nil {
-		return fiber.NewError(http.StatusServiceUnavailable, err.Error())
-	}
-	defer response.Body.Close()
-
-	var payload struct {
-		Hits struct {
-			Hits []struct {
-				Source Organization `json:"_source"`
-			} `json:"hits"`
-		} `json:"hits"`
-	}
-
-	if err = json.NewDecoder(response.Body).Decode(&payload); err != nil {
-		return err
-	}
-
-	organization := payload.Hits.Hits[0].Source
-	organization.Campaigns = append(organization.Campaigns, Campaign{
+	campaign := Campaign{
 		Key:          uuid.New(),
+		Organization: principal(c).Organization.Key,
 		Name:         request.Name,
 		Start:        request.Start,
 		Finish:       request.Finish,
@@ -370,6 +403,7 @@ func (s *server) CreateCampaign(c *fiber.Ctx) error {
 		Wanted:       request.Wanted,
 		Accept:       request.Accept,
 		Reject:       request.Reject,
+		Weights:      request.Weights,
 		Education:    request.Education,
 		Experience:   request.Experience,
 		Certificates: request.Certificates,
@@ -377,25 +411,22 @@ func (s *server) CreateCampaign(c *fiber.Ctx) error {
 		Skills:       request.Skills,
 		Languages:    request.Languages,
 		Created:      time.Now(),
-	})
+	}
 
-	data, err := json.Marshal(organization)
-	if err != nil {
-		return err
+	if err := validateCampaign(campaign); err != nil {
+		return fiber.NewError(http.StatusBadRequest, err.Error())
 	}
 
-	create := esapi.IndexRequest{
-		Index:      s.configuration.Index,
-		DocumentID: payload.Hits.Hits[0].Source.Key.String(),
-		Body:       bytes.NewReader(data),
+	if campaign.Active && !principal(c).Verified() {
+		return errUnverified
 	}
 
-	response, err = create.Do(context.Background(), s.storage)
+	version, err := s.campaigns.Save(c.UserContext(), campaign)
 	if err != nil {
-		return fiber.NewError(http.StatusServiceUnavailable, err.Error())
+		return storageError(err)
 	}
-	defer response.Body.Close()
 
+	c.Set(fiber.HeaderETag, version.ETag())
 	return c.SendString("campaign created")
 }
 
@@ -405,7 +436,11 @@ func (s *server) CreateCampaign(c *fiber.Ctx) error {
 // @Tags campaigns
 // @Accept application/json
 // @Param payload body UpdateCampaignRequest true "body"
+// @Param If-Match header string false "ETag of the campaign version being updated"
 // @Success 200 {object} string
+// @Failure 400
+// @Failure 404
+// @Failure 412
 // @Router /campaign/update [patch]
 func (s *server) UpdateCampaign(c *fiber.Ctx) error {
 	var request UpdateCampaignRequest
@@ -413,125 +448,83 @@ func (s *server) UpdateCampaign(c *fiber.Ctx) error {
 		return err
 	}
 
-	// TODO: refactor later
-	cookie := c.Cookies(s.configuration.Cookie)
-	parser := func(token *jwt.Token) (interface{}, error) { return []byte(s.configuration.Secret), nil }
-
-	token, err := jwt.Parse(cookie, parser)
-	if err != nil {
-		return fiber.NewError(http.StatusUnauthorized, "unauthorized")
-	}
-
-	claims := token.Claims.(jwt.MapClaims)
-	company := claims["Company"].(string)
-
-	query := strings.NewReader(fmt.Sprintf(`{ "query": { "match_phrase": { "name": "%s" } } }`, company))
-	response, err := s.storage.Search(s.storage.Search.WithIndex(s.configuration.Index), s.storage.Search.WithBody(query))
+	campaign, err := s.campaign(c, request.Key.String())
 	if err != nil {
-		return fiber.NewError(http.StatusServiceUnavailable, err.Error())
-	}
-	defer response.Body.Close()
-
-	var payload struct {
-		Hits struct {
-			Hits []struct {
-				Source Organization `json:"_source"`
-			} `json:"hits"`
-		} `json:"hits"`
-	}
-
-	if err = json.NewDecoder(response.Body).Decode(&payload); err != nil {
 		return err
 	}
 
-	var campaign Campaign
-	index := -1
-	for i, item := range payload.Hits.Hits[0].Source.Campaigns {
-		if item.Key == request.Key {
-			campaign = item
-			index = i
-			break
+	_, err = s.modifyCampaign(c, campaign, func(campaign *Campaign) error {
+		if request.Name != nil {
+			campaign.Name = *request.Name
 		}
-	}
-
-	if index == -1 {
-		return errors.New("Campaign with this id not found")
-	}
 
-	organization := payload.Hits.Hits[0].Source
+		if request.Start != nil {
+			campaign.Start = *request.Start
+		}
 
-	if request.Name != nil {
-		campaign.Name = *request.Name
-	}
+		if request.Finish != nil {
+			campaign.Finish = *request.Finish
+		}
 
-	if request.Start != nil {
-		campaign.Start = *request.Start
-	}
+		if request.Active != nil {
+			if *request.Active && !campaign.Active && !principal(c).Verified() {
+				return errUnverified
+			}
 
-	if request.Finish != nil {
-		campaign.Finish = *request.Finish
-	}
+			campaign.Active = *request.Active
+		}
 
-	if request.Active != nil {
-		campaign.Active = *request.Active
-	}
+		if request.Wanted != nil {
+			campaign.Wanted = *request.Wanted
+		}
 
-	if request.Wanted != nil {
-		campaign.Wanted = *request.Wanted
-	}
+		if request.Accept != nil {
+			campaign.Accept = *request.Accept
+		}
 
-	if request.Accept != nil {
-		campaign.Accept = *request.Accept
-	}
+		if request.Reject != nil {
+			campaign.Reject = *request.Reject
+		}
 
-	if request.Reject != nil {
-		campaign.Reject = *request.Reject
-	}
+		if request.Weights != nil {
+			campaign.Weights = request.Weights
+		}
 
-	if request.Education != nil {
-		campaign.Education = *request.Education
-	}
+		if request.Education != nil {
+			campaign.Education = *request.Education
+		}
 
-	if request.Experience != nil {
-		campaign.Experience = *request.Experience
-	}
+		if request.Experience != nil {
+			campaign.Experience = *request.Experience
+		}
 
-	if request.Certificates != nil {
-		campaign.Certificates = *request.Certificates
-	}
+		if request.Certificates != nil {
+			campaign.Certificates = *request.Certificates
+		}
 
-	if request.Courses != nil {
-		campaign.Courses = *request.Courses
-	}
+		if request.Courses != nil {
+			campaign.Courses = *request.Courses
+		}
 
-	if request.Skills != nil {
-		campaign.Skills = *request.Skills
-	}
+		if request.Skills != nil {
+			campaign.Skills = *request.Skills
+		}
 
-	if request.Languages != nil {
-		campaign.Languages = *request.Languages
-	}
+		if request.Languages != nil {
+			campaign.Languages = *request.Languages
+		}
 
-	campaign.Updated = time.Now()
-	organization.Campaigns[index] = campaign
+		if err := validateCampaign(*campaign); err != nil {
+			return fiber.NewError(http.StatusBadRequest, err.Error())
+		}
 
-	data, err := json.Marshal(organization)
+		campaign.Updated = time.Now()
+		return nil
+	})
 	if err != nil {
 		return err
 	}
 
-	create := esapi.IndexRequest{
-		Index:      s.configuration.Index,
-		DocumentID: payload.Hits.Hits[0].Source.Key.String(),
-		Body:       bytes.NewReader(data),
-	}
-
-	response, err = create.Do(context.Background(), s.storage)
-	if err != nil {
-		return fiber.NewError(http.StatusServiceUnavailable, err.Error())
-	}
-	defer response.Body.Close()
-
 	return c.SendString("campaign updated")
 }
 
@@ -541,73 +534,71 @@ func (s *server) UpdateCampaign(c *fiber.Ctx) error {
 // @Tags campaigns
 // @Accept application/json
 // @Param key path string true "key"
+// @Param If-Match header string false "ETag of the campaign version being removed"
 // @Success 200 {object} string
+// @Failure 400
+// @Failure 404
+// @Failure 412
 // @Router /campaign/remove/{key} [delete]
 func (s *server) RemoveCampaign(c *fiber.Ctx) error {
-	key := c.Params("key")
-
-	// TODO: refactor later
-	cookie := c.Cookies(s.configuration.Cookie)
-	parser := func(token *jwt.Token) (interface{}, error) { return []byte(s.configuration.Secret), nil }
-
-	token, err := jwt.Parse(cookie, parser)
+	campaign, err := s.campaign(c, c.Params("key"))
 	if err != nil {
-		return fiber.NewError(http.StatusUnauthorized, "unauthorized")
+		return err
 	}
 
-	claims := token.Claims.(jwt.MapClaims)
-	company := claims["Company"].(string)
-
-	query := strings.NewReader(fmt.Sprintf(`{ "query": { "match_phrase": { "name": "%s" } } }`, company))
-	response, err := s.storage.Search(s.storage.Search.WithIndex(s.configuration.Index), s.storage.Search.WithBody(query))
-	if err != nil {
-		return fiber.NewError(http.StatusServiceUnavailable, err.Error())
-	}
-	defer response.Body.Close()
+	for attempt := 1; ; attempt++ {
+		if err = precondition(c, campaign.Version); err != nil {
+			return err
+		}
 
-	var payload struct {
-		Hits struct {
-			Hits []struct {
-				Source Organization `json:"_source"`
-			} `json:"hits"`
-		} `json:"hits"`
-	}
+		err = s.campaigns.Remove(c.UserContext(), campaign)
+		if err == nil {
+			return c.SendString("campaign removed")
+		}
 
-	if err = json.NewDecoder(response.Body).Decode(&payload); err != nil {
-		return err
-	}
+		if !errors.Is(err, ErrConflict) || attempt == conflictRetries {
+			return storageError(err)
+		}
 
-	index := -1
-	for i, item := range payload.Hits.Hits[0].Source.Campaigns {
-		if item.Key.String() == key {
-			index = i
-			break
+		if campaign, err = s.campaign(c, campaign.Key.String()); err != nil {
+			return err
 		}
 	}
+}
 
-	if index == -1 {
-		return errors.New("Campaign with this id not found")
+// campaign loads a campaign of the caller's organization.
+func (s *server) campaign(c *fiber.Ctx, value string) (Campaign, error) {
+	key, err := uuid.Parse(value)
+	if err != nil {
+		return Campaign{}, fiber.NewError(http.StatusBadRequest, "invalid campaign key")
 	}
 
-	organization := payload.Hits.Hits[0].Source
-	organization.Campaigns = append(organization.Campaigns[:index], organization.Campaigns[index+1:]...)
-
-	data, err := json.Marshal(organization)
+	campaign, err := s.campaigns.FindByKey(c.UserContext(), key)
 	if err != nil {
-		return err
+		return Campaign{}, storageError(err)
 	}
 
-	create := esapi.IndexRequest{
-		Index:      s.configuration.Index,
-		DocumentID: payload.Hits.Hits[0].Source.Key.String(),
-		Body:       bytes.NewReader(data),
+	if campaign.Organization != principal(c).Organization.Key {
+		return Campaign{}, fiber.NewError(http.StatusNotFound, "campaign with this id not found")
 	}
 
-	response, err = create.Do(context.Background(), s.storage)
-	if err != nil {
+	return campaign, nil
+}
+
+func tooManyAttempts(c *fiber.Ctx, wait time.Duration) error {
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	return fiber.NewError(http.StatusTooManyRequests, "too many failed attempts, try again later")
+}
+
+var errUnverified = fiber.NewError(http.StatusForbidden, "verify your email before publishing campaigns")
+
+func storageError(err error) error {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return fiber.NewError(http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrConflict):
+		return fiber.NewError(http.StatusConflict, err.Error())
+	default:
 		return fiber.NewError(http.StatusServiceUnavailable, err.Error())
 	}
-	defer response.Body.Close()
-
-	return c.SendString("campaign removed")
 }
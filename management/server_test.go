@@ -0,0 +1,273 @@
+package management
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type testMailer struct {
+	mutex    sync.Mutex
+	messages []Message
+}
+
+func (m *testMailer) Send(ctx context.Context, message Message) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.messages = append(m.messages, message)
+	return nil
+}
+
+// last returns the last message sent to address.
+func (m *testMailer) last(t *testing.T, address string) Message {
+	t.Helper()
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		if m.messages[i].To == address {
+			return m.messages[i]
+		}
+	}
+
+	t.Fatalf("no message sent to %s", address)
+	return Message{}
+}
+
+// token extracts the token of the link in a message.
+func (m Message) token(t *testing.T) string {
+	t.Helper()
+	_, token, ok := strings.Cut(m.Body, "token=")
+	if !ok {
+		t.Fatalf("no token in %q", m.Body)
+	}
+
+	token, err := url.QueryUnescape(strings.TrimSpace(token))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return token
+}
+
+// link returns the path of the link to the server in a message, failing the
+// test unless it starts with prefix.
+func (s *testServer) link(t *testing.T, m Message, prefix string) string {
+	t.Helper()
+	_, link, ok := strings.Cut(m.Body, s.config.URL)
+	if fields := strings.Fields(link); len(fields) > 0 {
+		link = fields[0]
+	}
+
+	if !ok || !strings.HasPrefix(link, prefix) {
+		t.Fatalf("no link to %s in %q", prefix, m.Body)
+	}
+
+	return link
+}
+
+type testServer struct {
+	app     *fiber.App
+	storage Storage
+	mailer  *testMailer
+	config  Config
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	return newStorageTestServer(t, NewMemoryStorage())
+}
+
+// newStorageTestServer serves storage, which tests may wrap to interfere with
+// the requests.
+func newStorageTestServer(t *testing.T, storage Storage) *testServer {
+	t.Helper()
+	t.Setenv("MODE", ModeDevelopment)
+	if _, ok := os.LookupEnv("LOGIN_BACKOFF"); !ok {
+		t.Setenv("LOGIN_BACKOFF", "0")
+	}
+	config, err := NewConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyring, err := NewKeyring(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &testServer{app: fiber.New(), storage: storage, mailer: &testMailer{}, config: config}
+	NewServer(s.storage, s.mailer, keyring, config).Chain(s.app)
+	return s
+}
+
+// testClient keeps the cookies of a browser and sends the CSRF header the
+// frontend would.
+type testClient struct {
+	t       *testing.T
+	server  *testServer
+	cookies map[string]string
+}
+
+func (s *testServer) client(t *testing.T) *testClient {
+	return &testClient{t: t, server: s, cookies: map[string]string{}}
+}
+
+func (c *testClient) do(method, path, body string) (int, string) {
+	c.t.Helper()
+	response, data := c.send(httptest.NewRequest(method, path, strings.NewReader(body)))
+	return response.StatusCode, data
+}
+
+func (c *testClient) send(request *http.Request) (*http.Response, string) {
+	c.t.Helper()
+	request.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	for name, value := range c.cookies {
+		request.AddCookie(&http.Cookie{Name: name, Value: value})
+	}
+
+	if csrf, ok := c.cookies[c.server.config.CSRFCookie]; ok {
+		request.Header.Set(c.server.config.CSRFHeader, csrf)
+	}
+
+	response, err := c.server.app.Test(request, -1)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	for _, cookie := range response.Cookies() {
+		if cookie.Value == "" {
+			delete(c.cookies, cookie.Name)
+		} else {
+			c.cookies[cookie.Name] = cookie.Value
+		}
+	}
+
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+
+	return response, string(data)
+}
+
+// expect sends the request and fails the test unless it answers status.
+func (c *testClient) expect(status int, method, path, body string) string {
+	c.t.Helper()
+	code, data := c.do(method, path, body)
+	if code != status {
+		c.t.Fatalf("%s %s: got %d %s, want %d", method, path, code, data, status)
+	}
+
+	return data
+}
+
+func decode(t *testing.T, data string, target interface{}) {
+	t.Helper()
+	if err := json.Unmarshal([]byte(data), target); err != nil {
+		t.Fatalf("decode %q: %v", data, err)
+	}
+}
+
+func (s *testServer) register(t *testing.T, email, company string) *testClient {
+	t.Helper()
+	client := s.client(t)
+	client.expect(http.StatusOK, "POST", "/account/register", `{"email":"`+email+`","password":"secret1","company":"`+company+`"}`)
+	return client
+}
+
+// verify redeems the verification link last mailed to email.
+func (s *testServer) verify(t *testing.T, email string) {
+	t.Helper()
+	s.client(t).expect(http.StatusOK, "GET", s.link(t, s.mailer.last(t, email), "/account/verify?token="), "")
+}
+
+func TestRegister(t *testing.T) {
+	s := newTestServer(t)
+	client := s.register(t, "Owner@Example.com", "Acme")
+
+	var identity Identity
+	decode(t, client.expect(http.StatusOK, "GET", "/account/authorize", ""), &identity)
+	if identity.Email != "owner@example.com" || identity.Company != "Acme" || identity.Role != RoleOwner {
+		t.Fatalf("unexpected identity %+v", identity)
+	}
+
+	if s.mailer.last(t, "owner@example.com").Subject == "" {
+		t.Fatal("verification mail without subject")
+	}
+
+	for _, body := range []string{
+		`{"email":"owner@example.com","password":"secret1","company":"Other"}`,
+		`{"email":"other@example.com","password":"secret1","company":"ACME"}`,
+		`{"email":"other@example.com","password":"secret1"}`,
+		`{"email":"invalid","password":"secret1","company":"Other"}`,
+		`{"email":"other@example.com","password":"123","company":"Other"}`,
+	} {
+		s.client(t).expect(http.StatusBadRequest, "POST", "/account/register", body)
+	}
+}
+
+func TestLogin(t *testing.T) {
+	s := newTestServer(t)
+	s.register(t, "owner@example.com", "Acme")
+
+	client := s.client(t)
+	client.expect(http.StatusBadRequest, "POST", "/account/login", `{"email":"nobody@example.com","password":"secret1"}`)
+	client.expect(http.StatusBadRequest, "POST", "/account/login", `{"email":"owner@example.com","password":"wrong1"}`)
+	client.expect(http.StatusUnauthorized, "GET", "/account/authorize", "")
+
+	client.expect(http.StatusOK, "POST", "/account/login", `{"email":"OWNER@example.com","password":"secret1"}`)
+	client.expect(http.StatusOK, "GET", "/account/authorize", "")
+
+	client.expect(http.StatusOK, "POST", "/account/logout", "")
+	client.expect(http.StatusUnauthorized, "GET", "/account/authorize", "")
+}
+
+func TestCampaigns(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.register(t, "owner@example.com", "Acme")
+	other := s.register(t, "other@example.com", "Other")
+
+	owner.expect(http.StatusOK, "POST", "/campaign/create", `{"name":"Backend","wanted":2,"skills":["Go"]}`)
+
+	var campaigns []Campaign
+	decode(t, owner.expect(http.StatusOK, "GET", "/campaigns", ""), &campaigns)
+	if len(campaigns) != 1 || campaigns[0].Name != "Backend" || campaigns[0].Skills[0].Name != "Go" {
+		t.Fatalf("unexpected campaigns %+v", campaigns)
+	}
+
+	key := campaigns[0].Key.String()
+	owner.expect(http.StatusOK, "PATCH", "/campaign/update", `{"key":"`+key+`","name":"Frontend","wanted":3}`)
+
+	var campaign Campaign
+	decode(t, owner.expect(http.StatusOK, "GET", "/campaign/"+key, ""), &campaign)
+	if campaign.Name != "Frontend" || campaign.Wanted != 3 || campaign.Updated.IsZero() {
+		t.Fatalf("campaign not updated %+v", campaign)
+	}
+
+	owner.expect(http.StatusBadRequest, "PATCH", "/campaign/update", `{"key":"`+key+`","accept":2}`)
+
+	decode(t, other.expect(http.StatusOK, "GET", "/campaigns", ""), &campaigns)
+	if len(campaigns) != 0 {
+		t.Fatalf("campaigns of another organization listed %+v", campaigns)
+	}
+
+	other.expect(http.StatusNotFound, "GET", "/campaign/"+key, "")
+	other.expect(http.StatusNotFound, "PATCH", "/campaign/update", `{"key":"`+key+`","name":"Stolen"}`)
+	other.expect(http.StatusNotFound, "DELETE", "/campaign/remove/"+key, "")
+
+	owner.expect(http.StatusOK, "DELETE", "/campaign/remove/"+key, "")
+	owner.expect(http.StatusNotFound, "GET", "/campaign/"+key, "")
+	s.client(t).expect(http.StatusUnauthorized, "GET", "/campaigns", "")
+}
@@ -0,0 +1,208 @@
+package management
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// login starts a new session family for the account and issues both tokens.
+func (s *server) login(c *fiber.Ctx, organization Organization, account Account) error {
+	return s.rotate(c, organization, Session{
+		Family:       uuid.New(),
+		Organization: organization.Key,
+		Account:      account.Key,
+	})
+}
+
+// rotate stores a successor of previous in the same family and issues an access
+// token together with the refresh token of the new session.
+func (s *server) rotate(c *fiber.Ctx, organization Organization, previous Session) error {
+	token, digest, err := secret()
+	if err != nil {
+		return err
+	}
+
+	session := Session{
+		Key:          uuid.New(),
+		Family:       previous.Family,
+		Organization: previous.Organization,
+		Account:      previous.Account,
+		Token:        uuid.New(),
+		Secret:       digest,
+		Expires:      time.Now().Add(time.Duration(s.configuration.Expiration) * time.Hour),
+		Created:      time.Now(),
+	}
+
+	if _, err = s.sessions.Save(c.UserContext(), session); err != nil {
+		return storageError(err)
+	}
+
+	if err = s.issue(c, organization, session); err != nil {
+		return err
+	}
+
+	s.cookie(c, s.configuration.RefreshCookie, session.Key.String()+"."+token, session.Expires)
+	return s.csrf(c, session.Expires)
+}
+
+// @Summary Refresh
+// @Schemes
+// @Description Rotate the refresh token and issue a new access token
+// @Tags account
+// @Accept application/json
+// @Success 200 {object} string
+// @Failure 401
+// @Router /account/refresh [post]
+func (s *server) Refresh(c *fiber.Ctx) error {
+	session, err := s.session(c)
+	if err != nil {
+		return err
+	}
+
+	if session.Revoked || time.Now().After(session.Expires) {
+		return fiber.NewError(http.StatusUnauthorized, "session expired")
+	}
+
+	if session.Rotated {
+		if err := s.revoke(c, session.Family); err != nil {
+			return err
+		}
+		return fiber.NewError(http.StatusUnauthorized, "refresh token reused")
+	}
+
+	session.Rotated = true
+	if _, err = s.sessions.Save(c.UserContext(), session); errors.Is(err, ErrConflict) {
+		return fiber.NewError(http.StatusUnauthorized, "session already refreshed")
+	}
+
+	if err != nil {
+		return storageError(err)
+	}
+
+	organization, err := s.organizations.FindByKey(c.UserContext(), session.Organization)
+	if errors.Is(err, ErrNotFound) {
+		return fiber.NewError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	if err != nil {
+		return storageError(err)
+	}
+
+	if err = s.rotate(c, organization, session); err != nil {
+		return err
+	}
+
+	return c.SendString("session refreshed")
+}
+
+// session resolves the session behind the refresh cookie.
+func (s *server) session(c *fiber.Ctx) (Session, error) {
+	key, token, ok := split(c.Cookies(s.configuration.RefreshCookie))
+	if !ok {
+		return Session{}, fiber.NewError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	session, err := s.sessions.FindByKey(c.UserContext(), key)
+	if errors.Is(err, ErrNotFound) {
+		return Session{}, fiber.NewError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	if err != nil {
+		return Session{}, storageError(err)
+	}
+
+	if subtle.ConstantTimeCompare(session.Secret, hash(token)) != 1 {
+		return Session{}, fiber.NewError(http.StatusUnauthorized, "unauthorized")
+	}
+
+	return session, nil
+}
+
+// @Summary RevokeSessions
+// @Schemes
+// @Description Revoke every session of the current account
+// @Tags account
+// @Accept application/json
+// @Success 200 {object} string
+// @Failure 401
+// @Router /account/revoke [post]
+func (s *server) RevokeSessions(c *fiber.Ctx) error {
+	if err := s.revokeAccount(c, principal(c).Account.Key); err != nil {
+		return err
+	}
+
+	s.clearCookies(c)
+	return c.SendString("sessions revoked")
+}
+
+// revoke invalidates every session of the family.
+func (s *server) revoke(c *fiber.Ctx, family uuid.UUID) error {
+	sessions, err := s.sessions.ListByFamily(c.UserContext(), family)
+	if err != nil {
+		return storageError(err)
+	}
+
+	return s.revokeSessions(c, sessions)
+}
+
+// revokeAccount invalidates every session of the account, e.g. after its
+// credentials changed.
+func (s *server) revokeAccount(c *fiber.Ctx, account uuid.UUID) error {
+	sessions, err := s.sessions.ListByAccount(c.UserContext(), account)
+	if err != nil {
+		return storageError(err)
+	}
+
+	return s.revokeSessions(c, sessions)
+}
+
+// revokeSessions marks the sessions revoked, so their refresh tokens stop
+// working, and puts access tokens that did not expire yet on the revocation
+// list, dropping the entries of tokens expired meanwhile.
+func (s *server) revokeSessions(c *fiber.Ctx, sessions []Session) error {
+	var err error
+	revoked := false
+	for _, session := range sessions {
+		if expires := s.accessExpiry(session); time.Now().Before(expires) {
+			err = s.revocations.Revoke(c.UserContext(), Revocation{Token: session.Token, Expires: expires})
+			if err != nil {
+				return storageError(err)
+			}
+
+			revoked = true
+		}
+
+		for !session.Revoked {
+			session.Revoked = true
+			_, err = s.sessions.Save(c.UserContext(), session)
+			if !errors.Is(err, ErrConflict) {
+				break
+			}
+
+			// the session was rotated meanwhile, revoke its latest version
+			session, err = s.sessions.FindByKey(c.UserContext(), session.Key)
+			if err != nil {
+				break
+			}
+		}
+
+		if err != nil {
+			return storageError(err)
+		}
+	}
+
+	// the revocations are in place, a failed cleanup is left to the next one
+	if revoked {
+		if err = s.revocations.Prune(c.UserContext(), time.Now()); err != nil {
+			log.Printf("prune revocations: %v", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,131 @@
+package management
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// clone returns a client holding a copy of the cookies, as a thief of them
+// would.
+func (c *testClient) clone() *testClient {
+	cookies := map[string]string{}
+	for name, value := range c.cookies {
+		cookies[name] = value
+	}
+
+	return &testClient{t: c.t, server: c.server, cookies: cookies}
+}
+
+func TestRefresh(t *testing.T) {
+	s := newTestServer(t)
+	client := s.register(t, "owner@example.com", "Acme")
+	stolen := client.clone()
+
+	client.expect(http.StatusOK, "POST", "/account/refresh", "")
+	if client.cookies[s.config.RefreshCookie] == stolen.cookies[s.config.RefreshCookie] || client.cookies[s.config.Cookie] == stolen.cookies[s.config.Cookie] {
+		t.Fatal("tokens not rotated")
+	}
+
+	client.expect(http.StatusOK, "GET", "/account/authorize", "")
+	client.expect(http.StatusOK, "POST", "/account/refresh", "")
+
+	// presenting a rotated refresh token revokes the whole family
+	stolen.expect(http.StatusUnauthorized, "POST", "/account/refresh", "")
+	client.expect(http.StatusUnauthorized, "GET", "/account/authorize", "")
+	client.expect(http.StatusUnauthorized, "POST", "/account/refresh", "")
+
+	// other sessions of the account are not affected
+	other := s.client(t)
+	other.expect(http.StatusOK, "POST", "/account/login", `{"email":"owner@example.com","password":"secret1"}`)
+	other.expect(http.StatusOK, "POST", "/account/refresh", "")
+	other.expect(http.StatusOK, "GET", "/account/authorize", "")
+}
+
+func TestRefreshInvalid(t *testing.T) {
+	s := newTestServer(t)
+	client := s.register(t, "owner@example.com", "Acme")
+
+	for _, value := range []string{"", "invalid", "00000000-0000-0000-0000-000000000000.secret"} {
+		forged := client.clone()
+		forged.cookies[s.config.RefreshCookie] = value
+		forged.expect(http.StatusUnauthorized, "POST", "/account/refresh", "")
+	}
+
+	key, _, _ := split(client.cookies[s.config.RefreshCookie])
+	forged := client.clone()
+	forged.cookies[s.config.RefreshCookie] = key.String() + ".secret"
+	forged.expect(http.StatusUnauthorized, "POST", "/account/refresh", "")
+
+	client.expect(http.StatusOK, "POST", "/account/refresh", "")
+}
+
+func TestLogout(t *testing.T) {
+	s := newTestServer(t)
+	client := s.register(t, "owner@example.com", "Acme")
+	stolen := client.clone()
+
+	client.expect(http.StatusOK, "POST", "/account/logout", "")
+	if len(client.cookies) != 0 {
+		t.Fatalf("cookies left after logout %v", client.cookies)
+	}
+
+	// the tokens stop working on the server too
+	stolen.expect(http.StatusUnauthorized, "GET", "/account/authorize", "")
+	stolen.expect(http.StatusUnauthorized, "POST", "/account/refresh", "")
+}
+
+func TestRevokeSessions(t *testing.T) {
+	s := newTestServer(t)
+	client := s.register(t, "owner@example.com", "Acme")
+	other := s.client(t)
+	other.expect(http.StatusOK, "POST", "/account/login", `{"email":"owner@example.com","password":"secret1"}`)
+
+	stolen := client.clone()
+	client.expect(http.StatusOK, "POST", "/account/revoke", "")
+	for _, session := range []*testClient{stolen, other} {
+		session.expect(http.StatusUnauthorized, "GET", "/account/authorize", "")
+	}
+
+	other.expect(http.StatusUnauthorized, "POST", "/account/refresh", "")
+}
+
+func TestPruneRevocations(t *testing.T) {
+	ctx, now := context.Background(), time.Now()
+	revocations := NewMemoryStorage().Revocations
+	expired, current := Revocation{Token: uuid.New(), Expires: now.Add(-time.Second)}, Revocation{Token: uuid.New(), Expires: now.Add(time.Minute)}
+	for _, revocation := range []Revocation{expired, current} {
+		if err := revocations.Revoke(ctx, revocation); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := revocations.Prune(ctx, now); err != nil {
+		t.Fatal(err)
+	}
+
+	for token, want := range map[uuid.UUID]bool{expired.Token: false, current.Token: true} {
+		if revoked, err := revocations.IsRevoked(ctx, token); err != nil || revoked != want {
+			t.Fatalf("got revoked %v %v, want %v", revoked, err, want)
+		}
+	}
+}
+
+func TestPruneElasticRevocations(t *testing.T) {
+	cluster := &testCluster{}
+	m := testMigrator(t, cluster)
+	revocations := &elasticRevocations{documents: elasticDocuments{storage: m.storage, index: "revocations"}}
+
+	now := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	if err := revocations.Prune(context.Background(), now); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `POST /revocations/_delete_by_query {"query":{"range":{"expires":{"lt":"2022-10-01T12:00:00Z"}}}}`
+	if len(cluster.requests) != 1 || cluster.requests[0] != want {
+		t.Fatalf("got requests %v", cluster.requests)
+	}
+}
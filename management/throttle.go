@@ -0,0 +1,91 @@
+package management
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// throttle tracks failed attempts per key and delays further attempts with an
+// exponential backoff once more than free failures were recorded. It is kept
+// in process memory, the persistent account lockout covers other instances.
+type throttle struct {
+	mutex    sync.Mutex
+	base     time.Duration
+	max      time.Duration
+	attempts map[string]*attempt
+	pruned   time.Time
+}
+
+type attempt struct {
+	failures int
+	last     time.Time
+	next     time.Time
+}
+
+func newThrottle(base, max time.Duration) *throttle {
+	return &throttle{base: base, max: max, attempts: map[string]*attempt{}}
+}
+
+// Wait returns how long the key has to wait before its next attempt.
+func (t *throttle) Wait(key string, now time.Time) time.Duration {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	attempt, ok := t.attempts[key]
+	if !ok || now.After(attempt.next) {
+		return 0
+	}
+
+	return attempt.next.Sub(now)
+}
+
+// Fail records a failed attempt of the key.
+func (t *throttle) Fail(key string, free int, now time.Time) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.prune(now)
+
+	current, ok := t.attempts[key]
+	if !ok {
+		current = &attempt{}
+		t.attempts[key] = current
+	}
+
+	current.failures++
+	current.last = now
+	if current.failures <= free {
+		return
+	}
+
+	delay := time.Duration(float64(t.base) * math.Pow(2, float64(current.failures-free-1)))
+	if delay > t.max || delay < 0 {
+		delay = t.max
+	}
+
+	current.next = now.Add(delay)
+}
+
+// Reset forgets the failures of the key.
+func (t *throttle) Reset(key string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	delete(t.attempts, key)
+}
+
+// prune drops keys without failures during the longest backoff, so the map
+// does not grow with every address ever seen.
+func (t *throttle) prune(now time.Time) {
+	if now.Sub(t.pruned) < t.max {
+		return
+	}
+
+	t.pruned = now
+	for key, attempt := range t.attempts {
+		if now.Sub(attempt.last) > t.max && now.After(attempt.next) {
+			delete(t.attempts, key)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package management
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestThrottle(t *testing.T) {
+	now := time.Now()
+	throttle := newThrottle(time.Second, time.Minute)
+
+	for i, want := range []time.Duration{0, 0, time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second} {
+		throttle.Fail("ip:a", 2, now)
+		if wait := throttle.Wait("ip:a", now); wait != want {
+			t.Fatalf("failure %d: got %s, want %s", i+1, wait, want)
+		}
+	}
+
+	if wait := throttle.Wait("ip:a", now.Add(8*time.Second)); wait != 0 {
+		t.Fatalf("got %s after the backoff", wait)
+	}
+
+	if wait := throttle.Wait("ip:b", now); wait != 0 {
+		t.Fatalf("other key waits %s", wait)
+	}
+
+	throttle.Reset("ip:a")
+	if wait := throttle.Wait("ip:a", now); wait != 0 {
+		t.Fatalf("got %s after reset", wait)
+	}
+}
+
+func TestThrottleMax(t *testing.T) {
+	now := time.Now()
+	throttle := newThrottle(time.Second, time.Minute)
+
+	// the delay overflows long before the last failure
+	for i := 0; i < 100; i++ {
+		throttle.Fail("email:a", 0, now)
+		if wait := throttle.Wait("email:a", now); wait <= 0 || wait > time.Minute {
+			t.Fatalf("failure %d: got %s", i+1, wait)
+		}
+	}
+
+	if wait := throttle.Wait("email:a", now); wait != time.Minute {
+		t.Fatalf("got %s, want the maximum", wait)
+	}
+}
+
+func TestThrottlePrune(t *testing.T) {
+	now := time.Now()
+	throttle := newThrottle(time.Second, time.Minute)
+	throttle.Fail("ip:old", 0, now)
+	throttle.Fail("ip:free", 5, now)
+
+	later := now.Add(30 * time.Second)
+	throttle.Fail("ip:recent", 0, later)
+
+	// pruning runs at most once per maximum backoff
+	throttle.Fail("ip:new", 0, now.Add(50*time.Second))
+	if len(throttle.attempts) != 4 {
+		t.Fatalf("pruned too early, %d keys left", len(throttle.attempts))
+	}
+
+	throttle.Fail("ip:new", 0, now.Add(2*time.Minute))
+	for key, want := range map[string]bool{"ip:old": false, "ip:free": false, "ip:recent": false, "ip:new": true} {
+		if _, ok := throttle.attempts[key]; ok != want {
+			t.Errorf("%s kept %v, want %v", key, ok, want)
+		}
+	}
+
+	throttle.Fail("ip:recent", 0, now.Add(2*time.Minute+time.Second))
+	if len(throttle.attempts) != 2 {
+		t.Fatalf("got %d keys, want 2", len(throttle.attempts))
+	}
+}
+
+// retryAfter sends a login and returns its Retry-After header, failing the
+// test unless it is answered with 429.
+func retryAfter(t *testing.T, client *testClient, body string) int {
+	t.Helper()
+	response, data := client.send(httptest.NewRequest("POST", "/account/login", strings.NewReader(body)))
+	if response.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("got %d %s, want 429", response.StatusCode, data)
+	}
+
+	seconds, err := strconv.Atoi(response.Header.Get("Retry-After"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return seconds
+}
+
+func TestLoginBackoff(t *testing.T) {
+	t.Setenv("LOGIN_BACKOFF", "60")
+	s := newTestServer(t)
+	s.register(t, "owner@example.com", "Acme")
+
+	client := s.client(t)
+	client.expect(http.StatusBadRequest, "POST", "/account/login", `{"email":"owner@example.com","password":"wrong1"}`)
+
+	// even the correct password waits for the backoff of the email
+	if seconds := retryAfter(t, client, `{"email":"owner@example.com","password":"secret1"}`); seconds < 59 || seconds > 60 {
+		t.Fatalf("got Retry-After %d, want 60", seconds)
+	}
+
+	s.client(t).expect(http.StatusBadRequest, "POST", "/account/login", `{"email":"nobody@example.com","password":"secret1"}`)
+}
+
+func TestLoginLockout(t *testing.T) {
+	s := newTestServer(t)
+	s.register(t, "owner@example.com", "Acme")
+
+	client := s.client(t)
+	for i := 0; i < s.config.LoginAttempts; i++ {
+		client.expect(http.StatusBadRequest, "POST", "/account/login", `{"email":"owner@example.com","password":"wrong1"}`)
+	}
+
+	lockout := s.config.LoginLockout * 60
+	if seconds := retryAfter(t, s.client(t), `{"email":"owner@example.com","password":"secret1"}`); seconds < lockout-5 || seconds > lockout {
+		t.Fatalf("got Retry-After %d, want %d", seconds, lockout)
+	}
+}
@@ -0,0 +1,283 @@
+package management
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// @Summary Forgot
+// @Schemes
+// @Description Mail a password reset link, responding the same whether the account exists or not
+// @Tags account
+// @Accept application/json
+// @Param payload body ForgotRequest true "body"
+// @Success 200 {object} string
+// @Failure 400
+// @Router /account/forgot [post]
+func (s *server) Forgot(c *fiber.Ctx) error {
+	var request ForgotRequest
+	if err := json.Unmarshal(c.Body(), &request); err != nil {
+		return fiber.NewError(http.StatusBadRequest, err.Error())
+	}
+
+	const response = "if the account exists, a reset link was sent"
+
+	organization, err := s.organizations.FindByEmail(c.UserContext(), request.Email.String())
+	if errors.Is(err, ErrNotFound) {
+		return c.SendString(response)
+	}
+
+	if err != nil {
+		return storageError(err)
+	}
+
+	account, ok := organization.AccountByEmail(request.Email.String())
+	if !ok {
+		return c.SendString(response)
+	}
+
+	token, err := s.ticket(c, PurposeReset, organization, account, time.Duration(s.configuration.Reset)*time.Minute)
+	if err != nil {
+		return err
+	}
+
+	err = s.mailer.Send(c.UserContext(), Message{
+		To:      account.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Use the link below to choose a new password, it expires in %d minutes.\n\n%s/reset?token=%s",
+			s.configuration.Reset, s.configuration.URL, url.QueryEscape(token)),
+	})
+	if err != nil {
+		return fiber.NewError(http.StatusServiceUnavailable, err.Error())
+	}
+
+	return c.SendString(response)
+}
+
+// @Summary Reset
+// @Schemes
+// @Description Set a new password with a reset token, signing out every session
+// @Tags account
+// @Accept application/json
+// @Param payload body ResetRequest true "body"
+// @Success 200 {object} string
+// @Failure 400
+// @Router /account/reset [post]
+func (s *server) Reset(c *fiber.Ctx) error {
+	var request ResetRequest
+	if err := json.Unmarshal(c.Body(), &request); err != nil {
+		return fiber.NewError(http.StatusBadRequest, err.Error())
+	}
+
+	if request.Password == "" {
+		return fiber.NewError(http.StatusBadRequest, "password is required")
+	}
+
+	ticket, err := s.redeemTicket(c, request.Token, PurposeReset)
+	if err != nil {
+		return err
+	}
+
+	organization, err := s.organizations.FindByKey(c.UserContext(), ticket.Organization)
+	if err != nil {
+		return storageError(err)
+	}
+
+	password, err := bcrypt.GenerateFromPassword([]byte(request.Password), 14)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.modifyAccount(c, organization, ticket.Account, func(account *Account) error {
+		account.Password = password
+		return nil
+	})
+	if errors.Is(err, errNoAccount) {
+		return fiber.NewError(http.StatusBadRequest, "invalid token")
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if err = s.revokeAccount(c, ticket.Account); err != nil {
+		return err
+	}
+
+	return c.SendString("password reset")
+}
+
+// @Summary Verify
+// @Schemes
+// @Description Confirm the email address of an account with a verification token sent to its current address
+// @Tags account
+// @Accept application/json
+// @Param token query string true "verification token"
+// @Success 200 {object} string
+// @Failure 400
+// @Router /account/verify [get]
+func (s *server) Verify(c *fiber.Ctx) error {
+	ticket, err := s.redeemTicket(c, c.Query("token"), PurposeVerify)
+	if err != nil {
+		return err
+	}
+
+	organization, err := s.organizations.FindByKey(c.UserContext(), ticket.Organization)
+	if err != nil {
+		return storageError(err)
+	}
+
+	// a link sent to a previous address does not verify the current one
+	_, err = s.modifyAccount(c, organization, ticket.Account, func(account *Account) error {
+		if ticket.Email == "" || !strings.EqualFold(account.Email, ticket.Email) {
+			return errNoAccount
+		}
+
+		account.Verified = true
+		return nil
+	})
+	if errors.Is(err, errNoAccount) {
+		return fiber.NewError(http.StatusBadRequest, "invalid token")
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return c.SendString("email verified")
+}
+
+// @Summary ResendVerification
+// @Schemes
+// @Description Mail a new verification link to the current account
+// @Tags account
+// @Accept application/json
+// @Success 200 {object} string
+// @Failure 400
+// @Failure 401
+// @Router /account/verify/resend [post]
+func (s *server) ResendVerification(c *fiber.Ctx) error {
+	principal := principal(c)
+	if principal.Account.Verified {
+		return fiber.NewError(http.StatusBadRequest, "email already verified")
+	}
+
+	if err := s.verification(c, principal.Organization, principal.Account); err != nil {
+		return err
+	}
+
+	return c.SendString("verification link sent")
+}
+
+// verification mails a verification link to the account.
+func (s *server) verification(c *fiber.Ctx, organization Organization, account Account) error {
+	token, err := s.ticket(c, PurposeVerify, organization, account, time.Duration(s.configuration.Verification)*time.Hour)
+	if err != nil {
+		return err
+	}
+
+	err = s.mailer.Send(c.UserContext(), Message{
+		To:      account.Email,
+		Subject: "Verify your email",
+		Body: fmt.Sprintf("Use the link below to verify your email address, it expires in %d hours.\n\n%s/account/verify?token=%s",
+			s.configuration.Verification, s.configuration.URL, url.QueryEscape(token)),
+	})
+	if err != nil {
+		return fiber.NewError(http.StatusServiceUnavailable, err.Error())
+	}
+
+	return nil
+}
+
+// ticket stores a new ticket for the account and returns its token.
+func (s *server) ticket(c *fiber.Ctx, purpose Purpose, organization Organization, account Account, lifetime time.Duration) (string, error) {
+	token, digest, err := secret()
+	if err != nil {
+		return "", err
+	}
+
+	ticket := Ticket{
+		Key:          uuid.New(),
+		Purpose:      purpose,
+		Organization: organization.Key,
+		Account:      account.Key,
+		Email:        account.Email,
+		Secret:       digest,
+		Expires:      time.Now().Add(lifetime),
+		Created:      time.Now(),
+	}
+
+	if _, err = s.tickets.Save(c.UserContext(), ticket); err != nil {
+		return "", storageError(err)
+	}
+
+	return ticket.Key.String() + "." + token, nil
+}
+
+// redeemTicket validates a ticket token issued for purpose and marks the ticket
+// used, so it cannot be redeemed again.
+func (s *server) redeemTicket(c *fiber.Ctx, token string, purpose Purpose) (Ticket, error) {
+	ticket, err := s.findTicket(c, token, purpose)
+	if err != nil {
+		return Ticket{}, err
+	}
+
+	if err = s.useTicket(c, ticket); err != nil {
+		return Ticket{}, err
+	}
+
+	return ticket, nil
+}
+
+// findTicket validates a ticket token issued for purpose without using it.
+func (s *server) findTicket(c *fiber.Ctx, token string, purpose Purpose) (Ticket, error) {
+	invalid := fiber.NewError(http.StatusBadRequest, "invalid token")
+
+	key, value, ok := split(token)
+	if !ok {
+		return Ticket{}, invalid
+	}
+
+	ticket, err := s.tickets.FindByKey(c.UserContext(), key)
+	if errors.Is(err, ErrNotFound) {
+		return Ticket{}, invalid
+	}
+
+	if err != nil {
+		return Ticket{}, storageError(err)
+	}
+
+	if ticket.Purpose != purpose || subtle.ConstantTimeCompare(ticket.Secret, hash(value)) != 1 {
+		return Ticket{}, invalid
+	}
+
+	if ticket.Used || time.Now().After(ticket.Expires) {
+		return Ticket{}, fiber.NewError(http.StatusBadRequest, "token expired")
+	}
+
+	return ticket, nil
+}
+
+func (s *server) useTicket(c *fiber.Ctx, ticket Ticket) error {
+	ticket.Used = true
+	_, err := s.tickets.Save(c.UserContext(), ticket)
+	if errors.Is(err, ErrConflict) {
+		return fiber.NewError(http.StatusBadRequest, "token expired")
+	}
+
+	if err != nil {
+		return storageError(err)
+	}
+
+	return nil
+}
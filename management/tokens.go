@@ -0,0 +1,118 @@
+package management
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
+)
+
+// Claims are carried by session tokens. The registered claims identify the
+// token (jti), its issuer and lifetime while the subject is the account.
+type Claims struct {
+	jwt.StandardClaims
+	Organization uuid.UUID `json:"organization"`
+	Company      string    `json:"company"`
+	Account      uuid.UUID `json:"account"`
+	Role         Role      `json:"role"`
+	Session      uuid.UUID `json:"session"`
+}
+
+// issue signs the access token of the session and sets it as the session
+// cookie, both expiring after Config.Access minutes.
+func (s *server) issue(c *fiber.Ctx, organization Organization, session Session) error {
+	expires := s.accessExpiry(session)
+
+	var role Role
+	if account, ok := organization.AccountByKey(session.Account); ok {
+		role = account.Permissions()
+	}
+
+	claims := Claims{
+		StandardClaims: jwt.StandardClaims{
+			Id:        session.Token.String(),
+			Issuer:    s.configuration.Issuer,
+			Subject:   session.Account.String(),
+			IssuedAt:  session.Created.Unix(),
+			ExpiresAt: expires.Unix(),
+		},
+		Organization: organization.Key,
+		Company:      organization.Name,
+		Account:      session.Account,
+		Role:         role,
+		Session:      session.Key,
+	}
+
+	value, err := s.keyring.sign(claims)
+	if err != nil {
+		return err
+	}
+
+	s.cookie(c, s.configuration.Cookie, value, expires)
+	return nil
+}
+
+func (s *server) accessExpiry(session Session) time.Time {
+	return session.Created.Add(time.Duration(s.configuration.Access) * time.Minute)
+}
+
+func (s *server) cookie(c *fiber.Ctx, name, value string, expires time.Time) {
+	c.Cookie(s.policy(name, value, expires))
+}
+
+// policy applies the cookie attributes of Config to a cookie.
+func (s *server) policy(name, value string, expires time.Time) *fiber.Cookie {
+	return &fiber.Cookie{
+		Name:     name,
+		Value:    value,
+		Domain:   s.configuration.CookieDomain,
+		Path:     s.configuration.CookiePath,
+		Secure:   s.configuration.CookieSecure,
+		HTTPOnly: s.configuration.CookieHTTPOnly,
+		SameSite: s.configuration.CookieSameSite,
+		Expires:  expires,
+	}
+}
+
+func (s *server) clearCookies(c *fiber.Ctx) {
+	for _, name := range []string{s.configuration.Cookie, s.configuration.RefreshCookie, s.configuration.CSRFCookie} {
+		s.cookie(c, name, "", time.Now().Add(-time.Second))
+	}
+}
+
+// secret returns a random URL safe token together with the hash it is stored
+// under.
+func secret() (string, []byte, error) {
+	value := make([]byte, 32)
+	if _, err := rand.Read(value); err != nil {
+		return "", nil, err
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(value)
+	return token, hash(token), nil
+}
+
+// split separates a "<key>.<secret>" token issued for a stored document.
+func split(token string) (uuid.UUID, string, bool) {
+	separator := strings.IndexByte(token, '.')
+	if separator < 0 {
+		return uuid.Nil, "", false
+	}
+
+	key, err := uuid.Parse(token[:separator])
+	if err != nil {
+		return uuid.Nil, "", false
+	}
+
+	return key, token[separator+1:], true
+}
+
+func hash(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
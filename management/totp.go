@@ -0,0 +1,378 @@
+package management
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	errTOTPEnabled   = fiber.NewError(http.StatusBadRequest, "two-factor authentication already enabled")
+	errIncorrectCode = fiber.NewError(http.StatusBadRequest, "incorrect code")
+)
+
+const (
+	totpPeriod   = 30
+	totpDigits   = 6
+	totpSkew     = 1
+	recoverySize = 10
+)
+
+// @Summary EnrollTOTP
+// @Schemes
+// @Description Start enrolling a TOTP second factor for the current account, it applies once confirmed
+// @Tags account
+// @Accept application/json
+// @Success 200 {object} Enrollment
+// @Failure 400
+// @Failure 401
+// @Router /account/totp/enroll [post]
+func (s *server) EnrollTOTP(c *fiber.Ctx) error {
+	key := make([]byte, 20)
+	if _, err := rand.Read(key); err != nil {
+		return err
+	}
+
+	principal := principal(c)
+	_, err := s.modifyAccount(c, principal.Organization, principal.Account.Key, func(account *Account) error {
+		if account.TOTP.Enabled() {
+			return errTOTPEnabled
+		}
+
+		account.TOTP = &TOTP{Secret: key}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	encoded := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(key)
+	uri := url.URL{
+		Scheme: "otpauth",
+		Host:   "totp",
+		Path:   "/" + s.configuration.Issuer + ":" + principal.Account.Email,
+		RawQuery: url.Values{
+			"secret":    {encoded},
+			"issuer":    {s.configuration.Issuer},
+			"algorithm": {"SHA1"},
+			"digits":    {fmt.Sprint(totpDigits)},
+			"period":    {fmt.Sprint(totpPeriod)},
+		}.Encode(),
+	}
+
+	return c.JSON(Enrollment{Secret: encoded, URI: uri.String()})
+}
+
+// @Summary ConfirmTOTP
+// @Schemes
+// @Description Enable the enrolled TOTP with a first code, returning the recovery codes once
+// @Tags account
+// @Accept application/json
+// @Param payload body CodeRequest true "body"
+// @Success 200 {object} RecoveryCodes
+// @Failure 400
+// @Failure 401
+// @Router /account/totp/confirm [post]
+func (s *server) ConfirmTOTP(c *fiber.Ctx) error {
+	var request CodeRequest
+	if err := json.Unmarshal(c.Body(), &request); err != nil {
+		return fiber.NewError(http.StatusBadRequest, err.Error())
+	}
+
+	codes, hashes, err := recoveryCodes()
+	if err != nil {
+		return err
+	}
+
+	principal := principal(c)
+	_, err = s.modifyAccount(c, principal.Organization, principal.Account.Key, func(account *Account) error {
+		otp := account.TOTP
+		if otp == nil {
+			return fiber.NewError(http.StatusBadRequest, "no two-factor authentication enrolled")
+		}
+
+		if otp.Confirmed {
+			return errTOTPEnabled
+		}
+
+		counter, ok := otp.code(request.Code, time.Now())
+		if !ok {
+			return errIncorrectCode
+		}
+
+		otp.Confirmed = true
+		otp.Counter = counter
+		otp.Recovery = hashes
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(RecoveryCodes{Codes: codes})
+}
+
+// @Summary RegenerateRecovery
+// @Schemes
+// @Description Replace the recovery codes of the current account, confirmed with a code
+// @Tags account
+// @Accept application/json
+// @Param payload body CodeRequest true "body"
+// @Success 200 {object} RecoveryCodes
+// @Failure 400
+// @Failure 401
+// @Router /account/totp/recovery [post]
+func (s *server) RegenerateRecovery(c *fiber.Ctx) error {
+	var request CodeRequest
+	if err := json.Unmarshal(c.Body(), &request); err != nil {
+		return fiber.NewError(http.StatusBadRequest, err.Error())
+	}
+
+	codes, hashes, err := recoveryCodes()
+	if err != nil {
+		return err
+	}
+
+	principal := principal(c)
+	_, err = s.modifyAccount(c, principal.Organization, principal.Account.Key, func(account *Account) error {
+		if !account.TOTP.Enabled() {
+			return fiber.NewError(http.StatusBadRequest, "two-factor authentication is not enabled")
+		}
+
+		if !account.TOTP.Verify(request.Code, time.Now()) {
+			return errIncorrectCode
+		}
+
+		account.TOTP.Recovery = hashes
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(RecoveryCodes{Codes: codes})
+}
+
+// @Summary DisableTOTP
+// @Schemes
+// @Description Remove the second factor of the current account, confirmed with its password and a code
+// @Tags account
+// @Accept application/json
+// @Param payload body DisableTOTPRequest true "body"
+// @Success 200 {object} string
+// @Failure 400
+// @Failure 401
+// @Router /account/totp/disable [post]
+func (s *server) DisableTOTP(c *fiber.Ctx) error {
+	var request DisableTOTPRequest
+	if err := json.Unmarshal(c.Body(), &request); err != nil {
+		return fiber.NewError(http.StatusBadRequest, err.Error())
+	}
+
+	principal := principal(c)
+	if err := bcrypt.CompareHashAndPassword(principal.Account.Password, []byte(request.Password)); err != nil {
+		return fiber.NewError(http.StatusBadRequest, "incorrect password")
+	}
+
+	_, err := s.modifyAccount(c, principal.Organization, principal.Account.Key, func(account *Account) error {
+		if account.TOTP.Enabled() && !account.TOTP.Verify(request.Code, time.Now()) {
+			return errIncorrectCode
+		}
+
+		account.TOTP = nil
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.SendString("two-factor authentication disabled")
+}
+
+// @Summary VerifyLogin
+// @Schemes
+// @Description Complete a login challenge with a TOTP or recovery code
+// @Tags account
+// @Accept application/json
+// @Param payload body LoginVerifyRequest true "body"
+// @Success 200 {object} string
+// @Failure 400
+// @Failure 429
+// @Failure 503
+// @Router /account/login/verify [post]
+func (s *server) VerifyLogin(c *fiber.Ctx) error {
+	var request LoginVerifyRequest
+	if err := json.Unmarshal(c.Body(), &request); err != nil {
+		return fiber.NewError(http.StatusBadRequest, err.Error())
+	}
+
+	address := "ip:" + c.IP()
+	if wait := s.attempts.Wait(address, time.Now()); wait > 0 {
+		return tooManyAttempts(c, wait)
+	}
+
+	ticket, err := s.findTicket(c, request.Token, PurposeLogin)
+	if err != nil {
+		return err
+	}
+
+	organization, err := s.organizations.FindByKey(c.UserContext(), ticket.Organization)
+	if err != nil {
+		return storageError(err)
+	}
+
+	account, ok := organization.AccountByKey(ticket.Account)
+	if !ok || !account.TOTP.Enabled() {
+		return fiber.NewError(http.StatusBadRequest, "invalid token")
+	}
+
+	if wait := time.Until(account.Locked); wait > 0 {
+		return tooManyAttempts(c, wait)
+	}
+
+	// Verify consumes the time step or recovery code, which has to be stored
+	// before the session is issued.
+	verified, err := s.modifyAccount(c, organization, account.Key, func(account *Account) error {
+		if !account.TOTP.Verify(request.Code, time.Now()) {
+			return errIncorrectCode
+		}
+
+		account.Failures = 0
+		return nil
+	})
+	if errors.Is(err, errIncorrectCode) {
+		s.attempts.Fail(address, s.configuration.LoginIPAttempts, time.Now())
+		if err = s.failure(c, organization, account.Key); err != nil {
+			return err
+		}
+
+		return errIncorrectCode
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if err = s.useTicket(c, ticket); err != nil {
+		return err
+	}
+
+	if err = s.login(c, verified, account); err != nil {
+		return err
+	}
+
+	return c.SendString("user logged")
+}
+
+// challenge answers a correct password of an account with a second factor by
+// a short-lived ticket to be completed at /account/login/verify.
+func (s *server) challenge(c *fiber.Ctx, organization Organization, account Account) error {
+	challenge, err := s.loginTicket(c, organization, account)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(http.StatusAccepted).JSON(challenge)
+}
+
+// loginTicket stores a login challenge for the account.
+func (s *server) loginTicket(c *fiber.Ctx, organization Organization, account Account) (Challenge, error) {
+	lifetime := time.Duration(s.configuration.Challenge) * time.Minute
+	token, err := s.ticket(c, PurposeLogin, organization, account, lifetime)
+	if err != nil {
+		return Challenge{}, err
+	}
+
+	return Challenge{Token: token, Expires: time.Now().Add(lifetime)}, nil
+}
+
+// Verify accepts either a code of the current time step, within the allowed
+// skew, or an unused recovery code, consuming it. The TOTP has to be saved
+// afterwards.
+func (t *TOTP) Verify(code string, now time.Time) bool {
+	if counter, ok := t.code(code, now); ok {
+		t.Counter = counter
+		return true
+	}
+
+	digest := hash(normalizeRecovery(code))
+	for i, recovery := range t.Recovery {
+		if subtle.ConstantTimeCompare(recovery, digest) == 1 {
+			t.Recovery = append(t.Recovery[:i:i], t.Recovery[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// code returns the time step the code was generated for, refusing steps at or
+// before the last accepted one.
+func (t *TOTP) code(code string, now time.Time) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != totpDigits {
+		return 0, false
+	}
+
+	current := now.Unix() / totpPeriod
+	for counter := current - totpSkew; counter <= current+totpSkew; counter++ {
+		if counter <= t.Counter {
+			continue
+		}
+
+		if subtle.ConstantTimeCompare([]byte(totp(t.Secret, counter)), []byte(code)) == 1 {
+			return counter, true
+		}
+	}
+
+	return 0, false
+}
+
+// totp computes the RFC 6238 code of the time step counter.
+func totp(secret []byte, counter int64) string {
+	message := make([]byte, 8)
+	binary.BigEndian.PutUint64(message, uint64(counter))
+
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(message)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000)
+}
+
+// recoveryCodes returns fresh single-use recovery codes with their hashes.
+func recoveryCodes() ([]string, [][]byte, error) {
+	codes := make([]string, 0, recoverySize)
+	hashes := make([][]byte, 0, recoverySize)
+	for i := 0; i < recoverySize; i++ {
+		value := make([]byte, 5)
+		if _, err := rand.Read(value); err != nil {
+			return nil, nil, err
+		}
+
+		code := strings.ToLower(base32.StdEncoding.EncodeToString(value))
+		codes = append(codes, code[:4]+"-"+code[4:])
+		hashes = append(hashes, hash(code))
+	}
+
+	return codes, hashes, nil
+}
+
+func normalizeRecovery(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
@@ -0,0 +1,138 @@
+package management
+
+import (
+	"encoding/base32"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 key of the RFC 6238 test vectors.
+var rfcSecret = []byte("12345678901234567890")
+
+func TestTOTPVectors(t *testing.T) {
+	// RFC 6238 appendix B, truncated to the last six digits
+	vectors := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+
+	for seconds, code := range vectors {
+		if got := totp(rfcSecret, seconds/totpPeriod); got != code {
+			t.Errorf("T=%d: got %s, want %s", seconds, got, code)
+		}
+	}
+}
+
+func TestTOTPVerify(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := now.Unix() / totpPeriod
+	otp := TOTP{Secret: rfcSecret, Confirmed: true}
+
+	for _, code := range []string{"", "12345", "1234567", totp(rfcSecret, step+2), totp(rfcSecret, step-2)} {
+		if otp.Verify(code, now) {
+			t.Fatalf("accepted %q", code)
+		}
+	}
+
+	if !otp.Verify(" "+totp(rfcSecret, step)+" ", now) || otp.Counter != step {
+		t.Fatalf("current code rejected, counter %d", otp.Counter)
+	}
+
+	// replays and earlier steps within the skew are refused
+	for _, counter := range []int64{step, step - 1} {
+		if otp.Verify(totp(rfcSecret, counter), now) {
+			t.Fatalf("accepted step %d at or below counter %d", counter, otp.Counter)
+		}
+	}
+
+	if !otp.Verify(totp(rfcSecret, step+1), now) || otp.Counter != step+1 {
+		t.Fatalf("next step within the skew rejected, counter %d", otp.Counter)
+	}
+
+	if otp.Verify(totp(rfcSecret, step+1), now.Add(totpPeriod*time.Second)) {
+		t.Fatal("accepted a replay in the next period")
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, hashes, err := recoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(codes) != recoverySize || len(hashes) != recoverySize {
+		t.Fatalf("got %d codes and %d hashes", len(codes), len(hashes))
+	}
+
+	otp := TOTP{Secret: rfcSecret, Confirmed: true, Recovery: hashes}
+	now := time.Now()
+	for _, code := range []string{codes[0], strings.ToUpper(codes[1]), strings.ReplaceAll(codes[2], "-", "")} {
+		if !otp.Verify(code, now) {
+			t.Fatalf("recovery code %q rejected", code)
+		}
+
+		if otp.Verify(code, now) {
+			t.Fatalf("recovery code %q accepted twice", code)
+		}
+	}
+
+	if len(otp.Recovery) != recoverySize-3 {
+		t.Fatalf("got %d unused recovery codes, want %d", len(otp.Recovery), recoverySize-3)
+	}
+
+	if otp.Verify("aaaa-aaaa", now) {
+		t.Fatal("unknown recovery code accepted")
+	}
+}
+
+// enroll enables TOTP for the client account and returns its secret and
+// recovery codes.
+func enroll(t *testing.T, client *testClient) ([]byte, []string) {
+	t.Helper()
+	var enrollment Enrollment
+	decode(t, client.expect(http.StatusOK, "POST", "/account/totp/enroll", ""), &enrollment)
+
+	secret, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(enrollment.Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var recovery RecoveryCodes
+	code := totp(secret, time.Now().Unix()/totpPeriod)
+	decode(t, client.expect(http.StatusOK, "POST", "/account/totp/confirm", `{"code":"`+code+`"}`), &recovery)
+	return secret, recovery.Codes
+}
+
+func TestTOTPLogin(t *testing.T) {
+	s := newTestServer(t)
+	secret, recovery := enroll(t, s.register(t, "owner@example.com", "Acme"))
+
+	login := func() (*testClient, string) {
+		client := s.client(t)
+		var challenge Challenge
+		decode(t, client.expect(http.StatusAccepted, "POST", "/account/login", `{"email":"owner@example.com","password":"secret1"}`), &challenge)
+		client.expect(http.StatusUnauthorized, "GET", "/account/authorize", "")
+		return client, challenge.Token
+	}
+
+	// the confirming code used the current step already
+	code := totp(secret, time.Now().Unix()/totpPeriod+1)
+	client, token := login()
+	client.expect(http.StatusBadRequest, "POST", "/account/login/verify", `{"token":"`+token+`","code":"000000"}`)
+	client.expect(http.StatusOK, "POST", "/account/login/verify", `{"token":"`+token+`","code":"`+code+`"}`)
+	client.expect(http.StatusOK, "GET", "/account/authorize", "")
+	client.expect(http.StatusBadRequest, "POST", "/account/login/verify", `{"token":"`+token+`","code":"`+recovery[0]+`"}`)
+
+	client, token = login()
+	client.expect(http.StatusBadRequest, "POST", "/account/login/verify", `{"token":"`+token+`","code":"`+code+`"}`)
+	client.expect(http.StatusOK, "POST", "/account/login/verify", `{"token":"`+token+`","code":"`+recovery[0]+`"}`)
+
+	client, token = login()
+	client.expect(http.StatusBadRequest, "POST", "/account/login/verify", `{"token":"`+token+`","code":"`+recovery[0]+`"}`)
+}
@@ -1,42 +1,316 @@
 package management
 
 import (
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 type Organization struct {
-	Key       uuid.UUID  `json:"key"`
-	Name      string     `json:"name"`
-	Accounts  []Account  `json:"accounts"`
-	Campaigns []Campaign `json:"campaigns"`
+	Key      uuid.UUID `json:"key"`
+	Name     string    `json:"name"`
+	Accounts []Account `json:"accounts"`
+	// Campaigns were embedded before they got their own index, Migrate moves
+	// them out.
+	Campaigns []Campaign `json:"campaigns,omitempty"`
+	Keys      []APIKey   `json:"keys"`
 	Created   time.Time  `json:"created"`
+	Version   Version    `json:"-"`
+}
+
+func (o Organization) APIKey(key uuid.UUID) (APIKey, bool) {
+	for _, item := range o.Keys {
+		if item.Key == key {
+			return item, true
+		}
+	}
+
+	return APIKey{}, false
+}
+
+func (o Organization) AccountByEmail(email string) (Account, bool) {
+	for _, account := range o.Accounts {
+		if strings.EqualFold(account.Email, email) {
+			return account, true
+		}
+	}
+
+	return Account{}, false
+}
+
+func (o Organization) AccountByKey(key uuid.UUID) (Account, bool) {
+	for _, account := range o.Accounts {
+		if account.Key == key {
+			return account, true
+		}
+	}
+
+	return Account{}, false
 }
 
+// Member is the view of an Account shared with the organization, it never
+// includes the password hash.
+type Member struct {
+	Key      uuid.UUID `json:"key"`
+	Email    string    `json:"email"`
+	Role     Role      `json:"role"`
+	Verified bool      `json:"verified"`
+	TOTP     bool      `json:"totp"`
+	Created  time.Time `json:"created"`
+}
+
+func (a Account) Member() Member {
+	return Member{Key: a.Key, Email: a.Email, Role: a.Permissions(), Verified: a.Verified, TOTP: a.TOTP.Enabled(), Created: a.Created}
+}
+
+type Role string
+
+const (
+	RoleOwner     Role = "owner"
+	RoleAdmin     Role = "admin"
+	RoleRecruiter Role = "recruiter"
+	RoleViewer    Role = "viewer"
+)
+
 type Account struct {
 	Key      uuid.UUID `json:"key"`
 	Email    string    `json:"email"`
 	Password []byte    `json:"password"`
+	Role     Role      `json:"role"`
+	Verified bool      `json:"verified"`
+	Failures int       `json:"failures"`
+	Locked   time.Time `json:"locked"`
+	TOTP     *TOTP     `json:"totp,omitempty"`
+	Subject  string    `json:"subject,omitempty"`
 	Created  time.Time `json:"created"`
 }
 
+// TOTP is the second factor of an account. It only applies once Confirmed,
+// Recovery keeps the hashes of the unused recovery codes and Counter the last
+// accepted time step, so a code cannot be replayed.
+type TOTP struct {
+	Secret    []byte   `json:"secret"`
+	Confirmed bool     `json:"confirmed"`
+	Recovery  [][]byte `json:"recovery"`
+	Counter   int64    `json:"counter"`
+}
+
+func (t *TOTP) Enabled() bool {
+	return t != nil && t.Confirmed
+}
+
 // TODO: move it later
 type Campaign struct {
+	Key          uuid.UUID   `json:"key"`
+	Organization uuid.UUID   `json:"organization"`
+	Name         string      `json:"name"`
+	Start        time.Time   `json:"start"`
+	Finish       time.Time   `json:"finish"`
+	Active       bool        `json:"active"`
+	Wanted       int         `json:"wanted"`
+	Accept       float32     `json:"accept"`
+	Reject       float32     `json:"reject"`
+	Weights      *Weights    `json:"weights,omitempty"`
+	Education    []Criterion `json:"education"`
+	Experience   []Criterion `json:"experience"`
+	Certificates []Criterion `json:"certificates"`
+	Courses      []Criterion `json:"courses"`
+	Skills       []Criterion `json:"skills"`
+	Languages    []Criterion `json:"languages"`
+	Created      time.Time   `json:"created"`
+	Updated      time.Time   `json:"updated"`
+	Version      Version     `json:"-"`
+}
+
+// Criterion is a single requirement of a campaign. Criteria stored before
+// weights were introduced are plain strings and decode with weight 1.
+type Criterion struct {
+	Name      string  `json:"name" example:"Go"`
+	Weight    float32 `json:"weight" example:"1"`
+	Mandatory bool    `json:"mandatory"`
+}
+
+// Weights sets how much each criterion category contributes to the score.
+// Campaigns without weights treat every category equally.
+type Weights struct {
+	Education    float32 `json:"education"`
+	Experience   float32 `json:"experience"`
+	Certificates float32 `json:"certificates"`
+	Courses      float32 `json:"courses"`
+	Skills       float32 `json:"skills"`
+	Languages    float32 `json:"languages"`
+}
+
+var DefaultWeights = Weights{Education: 1, Experience: 1, Certificates: 1, Courses: 1, Skills: 1, Languages: 1}
+
+// Open reports whether the campaign accepts applications at the given time.
+func (c Campaign) Open(now time.Time) bool {
+	if !c.Active || now.Before(c.Start) {
+		return false
+	}
+
+	return c.Finish.IsZero() || now.Before(c.Finish)
+}
+
+type Application struct {
 	Key          uuid.UUID `json:"key"`
+	Organization uuid.UUID `json:"organization"`
+	Campaign     uuid.UUID `json:"campaign"`
 	Name         string    `json:"name"`
-	Start        time.Time `json:"start"`
-	Finish       time.Time `json:"finish"`
-	Active       bool      `json:"active"`
-	Wanted       int       `json:"wanted"`
-	Accept       float32   `json:"accept"`
-	Reject       float32   `json:"reject"`
+	Email        string    `json:"email"`
 	Education    []string  `json:"education"`
 	Experience   []string  `json:"experience"`
 	Certificates []string  `json:"certificates"`
 	Courses      []string  `json:"courses"`
 	Skills       []string  `json:"skills"`
 	Languages    []string  `json:"languages"`
+	Score        *Score    `json:"score,omitempty"`
+	Status       Status    `json:"status"`
 	Created      time.Time `json:"created"`
 	Updated      time.Time `json:"updated"`
+	Version      Version   `json:"-"`
+}
+
+type Status string
+
+const (
+	StatusSubmitted Status = "submitted"
+	StatusReview    Status = "review"
+	StatusAccepted  Status = "accepted"
+	StatusRejected  Status = "rejected"
+)
+
+// Session is the server side state of a refresh token. Every refresh rotates
+// the session into a new one of the same family, so presenting a rotated
+// session again reveals a stolen token. Token is the jti of the access token
+// issued together with the session.
+type Session struct {
+	Key          uuid.UUID `json:"key"`
+	Family       uuid.UUID `json:"family"`
+	Organization uuid.UUID `json:"organization"`
+	Account      uuid.UUID `json:"account"`
+	Token        uuid.UUID `json:"token"`
+	Secret       []byte    `json:"secret"`
+	Rotated      bool      `json:"rotated"`
+	Revoked      bool      `json:"revoked"`
+	Expires      time.Time `json:"expires"`
+	Created      time.Time `json:"created"`
+	Version      Version   `json:"-"`
+}
+
+// Revocation marks an access token, identified by its jti, as no longer valid
+// until it would have expired anyway.
+type Revocation struct {
+	Token   uuid.UUID `json:"token"`
+	Expires time.Time `json:"expires"`
+}
+
+// Identity describes the authenticated account without its credentials.
+type Identity struct {
+	Organization uuid.UUID `json:"organization"`
+	Company      string    `json:"company"`
+	Account      uuid.UUID `json:"account"`
+	Email        string    `json:"email"`
+	Role         Role      `json:"role"`
+}
+
+// Invitation lets the invited email register into an existing organization.
+// It is redeemed with a single-use token whose hash is kept in Secret.
+type Invitation struct {
+	Key          uuid.UUID `json:"key"`
+	Organization uuid.UUID `json:"organization"`
+	Email        string    `json:"email"`
+	Role         Role      `json:"role"`
+	Secret       []byte    `json:"secret,omitempty"`
+	Used         bool      `json:"used"`
+	Expires      time.Time `json:"expires"`
+	Created      time.Time `json:"created"`
+	Version      Version   `json:"-"`
+}
+
+type Scope string
+
+const (
+	ScopeCampaignsRead     Scope = "campaigns:read"
+	ScopeCampaignsWrite    Scope = "campaigns:write"
+	ScopeApplicationsRead  Scope = "applications:read"
+	ScopeApplicationsWrite Scope = "applications:write"
+)
+
+// APIKey authenticates integrations of an organization with a bearer token
+// limited to Scopes. Only the hash of the token is kept in Secret and a zero
+// Expires never expires.
+type APIKey struct {
+	Key     uuid.UUID `json:"key"`
+	Name    string    `json:"name"`
+	Secret  []byte    `json:"secret,omitempty"`
+	Scopes  []Scope   `json:"scopes"`
+	Expires time.Time `json:"expires"`
+	Created time.Time `json:"created"`
+}
+
+func (k APIKey) Allows(scope Scope) bool {
+	for _, item := range k.Scopes {
+		if item == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+type APIKeyResponse struct {
+	Key     uuid.UUID `json:"key"`
+	Token   string    `json:"token"`
+	Expires time.Time `json:"expires"`
+}
+
+// Challenge is returned by a login that still needs a second factor, its
+// token is exchanged together with a code for the session.
+type Challenge struct {
+	Token   string    `json:"token"`
+	Expires time.Time `json:"expires"`
+}
+
+// Enrollment carries the secret of a new TOTP, also as an otpauth URI to be
+// rendered as a QR code.
+type Enrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+type RecoveryCodes struct {
+	Codes []string `json:"codes"`
+}
+
+type InvitationResponse struct {
+	Key     uuid.UUID `json:"key"`
+	Token   string    `json:"token"`
+	Expires time.Time `json:"expires"`
+}
+
+type Purpose string
+
+const (
+	PurposeReset  Purpose = "reset"
+	PurposeVerify Purpose = "verify"
+	PurposeLogin  Purpose = "login"
+	PurposeEmail  Purpose = "email"
+)
+
+// Ticket is a single-use, time-limited token mailed to an account, such as a
+// password reset link. Only the hash of the token is kept in Secret and Email
+// is the address it was sent to.
+type Ticket struct {
+	Key          uuid.UUID `json:"key"`
+	Purpose      Purpose   `json:"purpose"`
+	Organization uuid.UUID `json:"organization"`
+	Account      uuid.UUID `json:"account"`
+	Email        string    `json:"email"`
+	Secret       []byte    `json:"secret"`
+	Used         bool      `json:"used"`
+	Expires      time.Time `json:"expires"`
+	Created      time.Time `json:"created"`
+	Version      Version   `json:"-"`
 }
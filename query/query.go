@@ -0,0 +1,130 @@
+// Package query builds Elasticsearch search bodies from typed clauses, so
+// values are always encoded as JSON instead of being spliced into the body.
+package query
+
+import "encoding/json"
+
+type object = map[string]interface{}
+
+// Query is a clause of the query DSL.
+type Query interface {
+	json.Marshaler
+}
+
+type leaf struct {
+	kind  string
+	field string
+	value interface{}
+}
+
+func (l leaf) MarshalJSON() ([]byte, error) {
+	return json.Marshal(object{l.kind: object{l.field: l.value}})
+}
+
+// Term matches the exact value of a keyword or numeric field.
+func Term(field string, value interface{}) Query {
+	return leaf{kind: "term", field: field, value: value}
+}
+
+// Terms matches any of the exact values of a field, none without values.
+func Terms(field string, values ...interface{}) Query {
+	if values == nil {
+		values = []interface{}{}
+	}
+
+	return leaf{kind: "terms", field: field, value: values}
+}
+
+// MatchPhrase matches the analyzed phrase of a text field.
+func MatchPhrase(field string, value interface{}) Query {
+	return leaf{kind: "match_phrase", field: field, value: value}
+}
+
+// Exists matches documents with any value in field.
+func Exists(field string) Query {
+	return exists(field)
+}
+
+type exists string
+
+func (e exists) MarshalJSON() ([]byte, error) {
+	return json.Marshal(object{"exists": object{"field": string(e)}})
+}
+
+// Nested matches documents with an object of the nested field path matching
+// query.
+func Nested(path string, query Query) Query {
+	return nested{path: path, query: query}
+}
+
+type nested struct {
+	path  string
+	query Query
+}
+
+func (n nested) MarshalJSON() ([]byte, error) {
+	return json.Marshal(object{"nested": object{"path": n.path, "query": n.query}})
+}
+
+// Bool combines clauses, Filter and MustNot do not contribute to the score.
+type Bool struct {
+	Must    []Query `json:"must,omitempty"`
+	Filter  []Query `json:"filter,omitempty"`
+	Should  []Query `json:"should,omitempty"`
+	MustNot []Query `json:"must_not,omitempty"`
+}
+
+func (b Bool) MarshalJSON() ([]byte, error) {
+	type clauses Bool
+	return json.Marshal(object{"bool": clauses(b)})
+}
+
+// Range matches values of Field within the set bounds, nil bounds are left
+// open.
+type Range struct {
+	Field string
+	Gt    interface{}
+	Gte   interface{}
+	Lt    interface{}
+	Lte   interface{}
+}
+
+func (r Range) MarshalJSON() ([]byte, error) {
+	bounds := object{}
+	for name, value := range map[string]interface{}{"gt": r.Gt, "gte": r.Gte, "lt": r.Lt, "lte": r.Lte} {
+		if value != nil {
+			bounds[name] = value
+		}
+	}
+
+	return json.Marshal(object{"range": object{r.Field: bounds}})
+}
+
+type Order string
+
+const (
+	Asc  Order = "asc"
+	Desc Order = "desc"
+)
+
+type Sort struct {
+	Field string
+	Order Order
+}
+
+func (s Sort) MarshalJSON() ([]byte, error) {
+	return json.Marshal(object{s.Field: object{"order": s.Order}})
+}
+
+// Source filters the fields returned in the _source of every hit.
+type Source struct {
+	Includes []string `json:"includes,omitempty"`
+	Excludes []string `json:"excludes,omitempty"`
+}
+
+// Search is the body of a search request.
+type Search struct {
+	Query  Query   `json:"query,omitempty"`
+	Sort   []Sort  `json:"sort,omitempty"`
+	Source *Source `json:"_source,omitempty"`
+}
@@ -0,0 +1,89 @@
+package query
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestMarshal(t *testing.T) {
+	date := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{"term", Term("key", "a"), `{"term":{"key":"a"}}`},
+		{"term quote and backslash", Term("name", `a"b\c`), `{"term":{"name":"a\"b\\c"}}`},
+		{"term injection", Term("name", `"}},{"match_all":{}}`), `{"term":{"name":"\"}},{\"match_all\":{}}"}}`},
+		{"term number", Term("wanted", 3), `{"term":{"wanted":3}}`},
+		{"terms", Terms("status", "accepted", `re"view`), `{"terms":{"status":["accepted","re\"view"]}}`},
+		{"terms empty", Terms("status"), `{"terms":{"status":[]}}`},
+		{"match phrase", MatchPhrase("name", `Jane "JD" Doe`), `{"match_phrase":{"name":"Jane \"JD\" Doe"}}`},
+		{"exists", Exists(`a\b`), `{"exists":{"field":"a\\b"}}`},
+		{"nested", Nested("accounts", Term("accounts.email", "a@example.com")), `{"nested":{"path":"accounts","query":{"term":{"accounts.email":"a@example.com"}}}}`},
+		{"bool empty", Bool{}, `{"bool":{}}`},
+		{
+			"bool",
+			Bool{Must: []Query{Term("a", 1)}, Filter: []Query{Term("b", 2)}, Should: []Query{Exists("c")}, MustNot: []Query{Term("d", `"`)}},
+			`{"bool":{"must":[{"term":{"a":1}}],"filter":[{"term":{"b":2}}],"should":[{"exists":{"field":"c"}}],"must_not":[{"term":{"d":"\""}}]}}`,
+		},
+		{"range open", Range{Field: "expires"}, `{"range":{"expires":{}}}`},
+		{"range lower", Range{Field: "expires", Gt: date}, `{"range":{"expires":{"gt":"2022-10-01T12:00:00Z"}}}`},
+		{"range bounds", Range{Field: "score", Gte: 0.5, Lt: 1}, `{"range":{"score":{"gte":0.5,"lt":1}}}`},
+		{"range all", Range{Field: "n", Gt: 1, Gte: 2, Lt: 3, Lte: 4}, `{"range":{"n":{"gt":1,"gte":2,"lt":3,"lte":4}}}`},
+		{"sort", Sort{Field: "created", Order: Desc}, `{"created":{"order":"desc"}}`},
+		{"source", Source{Includes: []string{"key"}, Excludes: []string{"secret"}}, `{"includes":["key"],"excludes":["secret"]}`},
+		{"source empty", Source{}, `{}`},
+		{"search empty", Search{}, `{}`},
+		{
+			"search",
+			Search{
+				Query:  Bool{Filter: []Query{Term("campaign", `x"\`)}},
+				Sort:   []Sort{{Field: "created", Order: Asc}, {Field: "key", Order: Desc}},
+				Source: &Source{Excludes: []string{"secret"}},
+			},
+			`{"query":{"bool":{"filter":[{"term":{"campaign":"x\"\\"}}]}},"sort":[{"created":{"order":"asc"}},{"key":{"order":"desc"}}],"_source":{"excludes":["secret"]}}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := json.Marshal(test.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(data) != test.want {
+				t.Fatalf("got %s, want %s", data, test.want)
+			}
+		})
+	}
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	value := `a"b\c'd` + "\n"
+	data, err := json.Marshal(Search{Query: Nested("accounts", Term("accounts.email", value))})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var body struct {
+		Query struct {
+			Nested struct {
+				Path  string `json:"path"`
+				Query struct {
+					Term map[string]string `json:"term"`
+				} `json:"query"`
+			} `json:"nested"`
+		} `json:"query"`
+	}
+
+	if err = json.Unmarshal(data, &body); err != nil {
+		t.Fatal(err)
+	}
+
+	if body.Query.Nested.Path != "accounts" || len(body.Query.Nested.Query.Term) != 1 || body.Query.Nested.Query.Term["accounts.email"] != value {
+		t.Fatalf("value not preserved in %s", data)
+	}
+}